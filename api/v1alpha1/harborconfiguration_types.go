@@ -17,6 +17,11 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/robfig/cron/v3"
 	apiextensions "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -27,19 +32,219 @@ func init() {
 
 type HarborConfigurationSpec struct {
 	HarborTarget HarborTarget `json:"harborTarget,omitempty"`
-	Registry     Registry     `json:"registry,omitempty"`
-	ProjectReq   ProjectReq   `json:"projectReq,omitempty"`
-	Replication  Replication  `json:"replication,omitempty"`
+	// Registry, ProjectReq and Replication are each optional; sections that
+	// are omitted are neither reconciled nor deleted.
+	Registry    *Registry    `json:"registry,omitempty"`
+	ProjectReq  *ProjectReq  `json:"projectReq,omitempty"`
+	Replication *Replication `json:"replication,omitempty"`
+
+	// Additional registries, reconciled after Registry.
+	Registries []Registry `json:"registries,omitempty"`
+	// Additional projects, reconciled after ProjectReq.
+	Projects []ProjectReq `json:"projects,omitempty"`
+	// Additional replication rules, reconciled after Replication.
+	Replications []Replication `json:"replications,omitempty"`
+
+	// DeletionPolicy decides whether the Harbor objects are deleted or left
+	// in Harbor when the HarborConfiguration is deleted. Registries, projects
+	// and replication rules can override it individually.
+	// +kubebuilder:default=Delete
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// AdoptionPolicy decides whether Harbor objects that already exist and
+	// are not managed by this HarborConfiguration are taken over.
+	// +kubebuilder:default=Never
+	AdoptionPolicy AdoptionPolicy `json:"adoptionPolicy,omitempty"`
+}
+
+// DeletionPolicy decides what happens to a Harbor object when the resource
+// managing it is deleted.
+// +kubebuilder:validation:Enum=Delete;Orphan
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the object from Harbor.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyOrphan leaves the object in Harbor, detached from the operator.
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+)
+
+// AdoptionPolicy decides whether a resource takes over a Harbor object that
+// already exists and is not managed by it.
+// +kubebuilder:validation:Enum=Never;IfUnowned;Always
+type AdoptionPolicy string
+
+const (
+	// AdoptionPolicyNever reports a conflict instead of taking over the object.
+	AdoptionPolicyNever AdoptionPolicy = "Never"
+	// AdoptionPolicyIfUnowned takes over objects not managed by any resource.
+	AdoptionPolicyIfUnowned AdoptionPolicy = "IfUnowned"
+	// AdoptionPolicyAlways takes over the object, even from another resource.
+	AdoptionPolicyAlways AdoptionPolicy = "Always"
+)
+
+// Or returns the policy, or fallback when the policy is not set.
+func (p DeletionPolicy) Or(fallback DeletionPolicy) DeletionPolicy {
+	if p == "" {
+		return fallback
+	}
+	return p
+}
+
+// AllRegistries returns Registry, if set, followed by the entries of Registries.
+func (s HarborConfigurationSpec) AllRegistries() []Registry {
+	if s.Registry == nil {
+		return s.Registries
+	}
+	return append([]Registry{*s.Registry}, s.Registries...)
+}
+
+// AllProjects returns ProjectReq, if set, followed by the entries of Projects.
+func (s HarborConfigurationSpec) AllProjects() []ProjectReq {
+	if s.ProjectReq == nil {
+		return s.Projects
+	}
+	return append([]ProjectReq{*s.ProjectReq}, s.Projects...)
+}
+
+// AllReplications returns Replication, if set, followed by the entries of Replications.
+func (s HarborConfigurationSpec) AllReplications() []Replication {
+	if s.Replication == nil {
+		return s.Replications
+	}
+	return append([]Replication{*s.Replication}, s.Replications...)
 }
 
 type HarborConfigurationStatus struct {
 	RegistryId    int64  `json:"registryId,omitempty"`
 	ProjectId     string `json:"projectId,omitempty"`
 	ReplicationId int64  `json:"replicationId,omitempty"`
+
+	// Per-item reconciliation state, one entry per declared object.
+	Registries   []RegistryStatus    `json:"registries,omitempty"`
+	Projects     []ProjectStatus     `json:"projects,omitempty"`
+	Replications []ReplicationStatus `json:"replications,omitempty"`
+
+	// ObservedGeneration is the most recent generation reconciled by the controller.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions describe the reconciliation state of the Harbor objects.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+type RegistryStatus struct {
+	Name    string `json:"name"`
+	ID      int64  `json:"id,omitempty"`
+	Ready   bool   `json:"ready"`
+	Message string `json:"message,omitempty"`
+}
+
+type ProjectStatus struct {
+	Name    string `json:"name"`
+	ID      string `json:"id,omitempty"`
+	Ready   bool   `json:"ready"`
+	Message string `json:"message,omitempty"`
+}
+
+type ReplicationStatus struct {
+	Name    string `json:"name"`
+	ID      int64  `json:"id,omitempty"`
+	Ready   bool   `json:"ready"`
+	Message string `json:"message,omitempty"`
+	// NextScheduledTime is the next run of a scheduled trigger.
+	NextScheduledTime *metav1.Time `json:"nextScheduledTime,omitempty"`
+
+	ReplicationRunStatus `json:",inline"`
+}
+
+// ReplicationRunStatus records the replication executions started by the controller.
+type ReplicationRunStatus struct {
+	// RunRequest that started the last execution.
+	LastRunRequest string `json:"lastRunRequest,omitempty"`
+	// Hash of the replication rule the last execution was started for.
+	LastTriggeredSpecHash string `json:"lastTriggeredSpecHash,omitempty"`
+	// ID of the last execution started by the controller.
+	LastExecutionID int64 `json:"lastExecutionId,omitempty"`
+	// LastExecution reports the progress of the last execution started by the controller.
+	LastExecution *ReplicationExecutionStatus `json:"lastExecution,omitempty"`
+}
+
+// ReplicationExecutionStatus reports the progress of a replication execution
+// as seen by Harbor.
+type ReplicationExecutionStatus struct {
+	ID int64 `json:"id"`
+	// Status of the execution, one of InProgress, Succeed, Failed or Stopped.
+	Status     string `json:"status,omitempty"`
+	StatusText string `json:"statusText,omitempty"`
+	// Task counts of the execution.
+	Total      int64        `json:"total"`
+	Succeeded  int64        `json:"succeeded"`
+	Failed     int64        `json:"failed"`
+	InProgress int64        `json:"inProgress"`
+	Stopped    int64        `json:"stopped"`
+	StartTime  *metav1.Time `json:"startTime,omitempty"`
+	EndTime    *metav1.Time `json:"endTime,omitempty"`
+}
+
+const (
+	// ConditionReady is true when every Harbor object declared in the spec has been reconciled.
+	ConditionReady = "Ready"
+	// ConditionRegistryReady is true when every registry exists in Harbor and matches the spec.
+	ConditionRegistryReady = "RegistryReady"
+	// ConditionProjectReady is true when every project exists in Harbor and matches the spec.
+	ConditionProjectReady = "ProjectReady"
+	// ConditionReplicationReady is true when every replication rule exists in Harbor and matches the spec.
+	ConditionReplicationReady = "ReplicationReady"
+	// ConditionReplicationSucceeded is true when the last execution of every replication rule succeeded.
+	ConditionReplicationSucceeded = "ReplicationSucceeded"
+	// ConditionDrifted is true when the last reconciliation had to correct Harbor objects that no longer matched the spec.
+	ConditionDrifted = "Drifted"
+	// ConditionConflict is true when Harbor objects exist that the resource does not own and may not adopt.
+	ConditionConflict = "Conflict"
+)
+
+const (
+	ReasonReconciled               = "Reconciled"
+	ReasonReconcileFailed          = "ReconcileFailed"
+	ReasonHarborUnavailable        = "HarborUnavailable"
+	ReasonReplicationTriggerFailed = "ReplicationTriggerFailed"
+	ReasonRegistryNotReady         = "RegistryNotReady"
+	ReasonRegistryInUse            = "RegistryInUse"
+	ReasonReplicationInProgress    = "ReplicationInProgress"
+	ReasonReplicationSucceeded     = "ReplicationSucceeded"
+	ReasonReplicationFailed        = "ReplicationFailed"
+	ReasonDriftCorrected           = "DriftCorrected"
+	ReasonNoDrift                  = "NoDrift"
+	ReasonOwnershipConflict        = "OwnershipConflict"
+	ReasonNoConflict               = "NoConflict"
+	ReasonSecretDistributionFailed = "SecretDistributionFailed"
+	ReasonRobotAccountForbidden    = "RobotAccountForbidden"
+)
+
+// Reasons of the events recorded for the Harbor objects a resource manages.
+const (
+	ReasonCreated              = "Created"
+	ReasonUpdated              = "Updated"
+	ReasonAdopted              = "Adopted"
+	ReasonDeleted              = "Deleted"
+	ReasonDeleteFailed         = "DeleteFailed"
+	ReasonDeleteAbandoned      = "DeleteAbandoned"
+	ReasonOrphaned             = "Orphaned"
+	ReasonReleaseFailed        = "ReleaseFailed"
+	ReasonReplicationTriggered = "ReplicationTriggered"
+	ReasonReplicationDisabled  = "ReplicationDisabled"
+	ReasonCredentialsRefreshed = "CredentialsRefreshed"
+)
+
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+//+kubebuilder:printcolumn:name="Registry ID",type="integer",JSONPath=".status.registryId"
+//+kubebuilder:printcolumn:name="Project ID",type="string",JSONPath=".status.projectId"
+//+kubebuilder:printcolumn:name="Replication ID",type="integer",JSONPath=".status.replicationId"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 type HarborConfiguration struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -61,6 +266,55 @@ type HarborTarget struct {
 	Name           string `json:"name,omitempty"`
 	Namespace      string `json:"namespace,omitempty"`
 	HarborUsername string `json:"harborUsername,omitempty"`
+	// InstanceRef is the name of the HarborInstance to configure. When set,
+	// name and namespace are ignored.
+	InstanceRef string `json:"instanceRef,omitempty"`
+	// URL of a Harbor to configure directly, e.g. one installed from the
+	// upstream Helm chart. Requires credentialsSecretRef.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url,omitempty"`
+	// CredentialsSecretRef refers to the Secret holding the credentials for
+	// url. It is read from the namespace of the resource.
+	CredentialsSecretRef *LocalHarborCredentialsSecretReference `json:"credentialsSecretRef,omitempty"`
+	// TLS configures the connection to Harbor. It takes precedence over the
+	// TLS settings of a HarborInstance.
+	TLS *HarborTLS `json:"tls,omitempty"`
+}
+
+type HarborTLS struct {
+	// CARef refers to a PEM encoded CA bundle used to verify the certificate
+	// of Harbor. The system roots are used when unset.
+	CARef *CABundleRef `json:"caRef,omitempty"`
+
+	// ClientCertificateSecretRef refers to a kubernetes.io/tls Secret holding
+	// the client certificate presented to Harbor.
+	ClientCertificateSecretRef *TLSSecretRef `json:"clientCertificateSecretRef,omitempty"`
+
+	// ServerName is used to verify the certificate of Harbor instead of the
+	// host name of its URL.
+	ServerName string `json:"serverName,omitempty"`
+
+	// InsecureSkipVerify disables the verification of the certificate of Harbor.
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+type CABundleRef struct {
+	// Kind of the object holding the CA bundle.
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	// +kubebuilder:default=ConfigMap
+	Kind string `json:"kind,omitempty"`
+
+	// Name of the ConfigMap or Secret, which is read from the namespace of
+	// the resource.
+	Name string `json:"name"`
+
+	// Key holding the CA bundle, defaults to 'ca.crt'.
+	Key string `json:"key,omitempty"`
+}
+
+type TLSSecretRef struct {
+	// Name of the Secret, which is read from the namespace of the resource.
+	Name string `json:"name"`
 }
 
 type Registry struct {
@@ -69,6 +323,8 @@ type Registry struct {
 	EndpointUrl string              `json:"endpointUrl,omitempty"`
 	Description string              `json:"description,omitempty"`
 	Credential  *RegistryCredential `json:"credential,omitempty"`
+	// DeletionPolicy overrides the deletion policy for this registry.
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
 }
 
 type RegistryCredential struct {
@@ -81,24 +337,182 @@ type RegistryCredential struct {
 
 	// Credential type, such as 'basic', 'oauth'.
 	Type string `json:"type,omitempty"`
+
+	// Reference to a Secret holding the access key and secret.
+	// When set, it takes precedence over AccessKey and AccessSecret.
+	SecretRef *CredentialSecretRef `json:"secretRef,omitempty"`
+}
+
+type CredentialSecretRef struct {
+	// Name of the Secret, which is read from the namespace of the referencing
+	// object.
+	Name string `json:"name"`
+
+	// Key in the Secret holding the access key, defaults to 'access_key'.
+	AccessKeyKey string `json:"accessKeyKey,omitempty"`
+
+	// Key in the Secret holding the access secret, defaults to 'access_secret'.
+	AccessSecretKey string `json:"accessSecretKey,omitempty"`
 }
 
 type ProjectReq struct {
-	ProjectName            string `json:"projectName,omitempty"`
-	StorageQuota           *int64 `json:"storageQuota,omitempty"`
-	Public                 *bool  `json:"public,omitempty"`
+	ProjectName     string `json:"projectName,omitempty"`
+	ProjectSettings `json:",inline"`
+	// Name of the registry the project acts as a proxy cache for.
+	// Leave empty for a regular project.
 	ProxyCacheRegistryName string `json:"proxyCacheRegistryName,omitempty"`
 }
 
+// ProjectSettings holds the project configuration shared by ProjectReq and HarborProject.
+type ProjectSettings struct {
+	StorageQuota *int64 `json:"storageQuota,omitempty"`
+	Public       *bool  `json:"public,omitempty"`
+	// Metadata holds the scanning and content trust settings of the project.
+	Metadata *ProjectMetadata `json:"metadata,omitempty"`
+	// Members of the project. When set, members missing from the list are
+	// removed from the project; leave unset to manage members in Harbor.
+	Members []ProjectMember `json:"members,omitempty"`
+	// DeletionPolicy overrides the deletion policy for this project. Orphan
+	// keeps the project and its artifacts in Harbor.
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+// ProjectMetadata holds the project settings Harbor keeps as metadata.
+// Settings left unset are not changed in Harbor.
+type ProjectMetadata struct {
+	// AutoScan scans images for vulnerabilities when they are pushed.
+	AutoScan *bool `json:"autoScan,omitempty"`
+	// EnableContentTrust only allows pulling images signed with Notary.
+	EnableContentTrust *bool `json:"enableContentTrust,omitempty"`
+	// EnableContentTrustCosign only allows pulling images signed with cosign.
+	EnableContentTrustCosign *bool `json:"enableContentTrustCosign,omitempty"`
+	// PreventVul prevents pulling images with vulnerabilities of the given
+	// severity or above.
+	PreventVul *bool `json:"preventVul,omitempty"`
+	// Severity from which preventVul blocks images.
+	// +kubebuilder:validation:Enum=none;low;medium;high;critical
+	Severity string `json:"severity,omitempty"`
+	// ReuseSysCVEAllowlist applies the system CVE allowlist to the project.
+	ReuseSysCVEAllowlist *bool `json:"reuseSysCVEAllowlist,omitempty"`
+}
+
+// ProjectMember grants a user or group a role in a project.
+type ProjectMember struct {
+	// Name of the user or group in Harbor.
+	Name string `json:"name"`
+	// Kind of the member.
+	// +kubebuilder:validation:Enum=user;ldapGroup;oidcGroup
+	// +kubebuilder:default=user
+	Kind ProjectMemberKind `json:"kind,omitempty"`
+	// LDAPGroupDN is the DN of an ldapGroup member, needed to add it.
+	LDAPGroupDN string `json:"ldapGroupDN,omitempty"`
+	// Role of the member in the project.
+	// +kubebuilder:validation:Enum=projectAdmin;maintainer;developer;guest;limitedGuest
+	Role ProjectMemberRole `json:"role"`
+}
+
+// ProjectMemberKind is the kind of user or group a project member is.
+type ProjectMemberKind string
+
+const (
+	ProjectMemberKindUser      ProjectMemberKind = "user"
+	ProjectMemberKindLDAPGroup ProjectMemberKind = "ldapGroup"
+	ProjectMemberKindOIDCGroup ProjectMemberKind = "oidcGroup"
+)
+
+// ProjectMemberRole is the role of a member in a project.
+type ProjectMemberRole string
+
+const (
+	ProjectMemberRoleProjectAdmin ProjectMemberRole = "projectAdmin"
+	ProjectMemberRoleMaintainer   ProjectMemberRole = "maintainer"
+	ProjectMemberRoleDeveloper    ProjectMemberRole = "developer"
+	ProjectMemberRoleGuest        ProjectMemberRole = "guest"
+	ProjectMemberRoleLimitedGuest ProjectMemberRole = "limitedGuest"
+)
+
 type Replication struct {
-	Name                 string               `json:"name,omitempty"`
-	RegistryName         string               `json:"registryName,omitempty"`
-	DestinationNamespace string               `json:"destinationNamespace,omitempty"`
-	Description          string               `json:"description,omitempty"`
-	DestinationRegistry  *apiextensions.JSON  `json:"destinationRegistry,omitempty"`
-	EnablePolicy         bool                 `json:"enablePolicy,omitempty"`
-	ReplicateDeletion    bool                 `json:"replicateDeletion,omitempty"`
-	Override             bool                 `json:"override,omitempty"`
-	Filters              []apiextensions.JSON `json:"filters,omitempty"`
-	TriggerMode          *apiextensions.JSON  `json:"triggerMode,omitempty"`
+	Name                string `json:"name,omitempty"`
+	RegistryName        string `json:"registryName,omitempty"`
+	ReplicationSettings `json:",inline"`
+}
+
+// ReplicationSettings holds the replication rule configuration shared by
+// Replication and HarborReplicationPolicy.
+type ReplicationSettings struct {
+	DestinationNamespace string              `json:"destinationNamespace,omitempty"`
+	Description          string              `json:"description,omitempty"`
+	DestinationRegistry  *apiextensions.JSON `json:"destinationRegistry,omitempty"`
+	EnablePolicy         bool                `json:"enablePolicy,omitempty"`
+	ReplicateDeletion    bool                `json:"replicateDeletion,omitempty"`
+	Override             bool                `json:"override,omitempty"`
+	Filters              []ReplicationFilter `json:"filters,omitempty"`
+	TriggerMode          *ReplicationTrigger `json:"triggerMode,omitempty"`
+	// DeletionPolicy overrides the deletion policy for this replication rule.
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// Setting RunRequest to a new value, e.g. a timestamp, starts a manual
+	// replication execution. Executions are otherwise only started when the
+	// replication rule changes.
+	RunRequest string `json:"runRequest,omitempty"`
+}
+
+type ReplicationFilter struct {
+	// Type of the filter.
+	// +kubebuilder:validation:Enum=name;tag;label;resource
+	Type string `json:"type"`
+
+	// Value of the filter. For 'name' and 'tag' filters this is a pattern,
+	// e.g. 'library/**'. For 'label' filters this is a comma-separated list
+	// of label names, or a list of them. For 'resource' filters this is
+	// 'image' or 'artifact'.
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Value ReplicationFilterValue `json:"value"`
+
+	// Whether artifacts matching the filter are included or excluded.
+	// Only supported by 'tag' and 'label' filters.
+	// +kubebuilder:validation:Enum=matches;excludes
+	// +optional
+	Decoration string `json:"decoration,omitempty"`
+}
+
+// ReplicationFilterValue is the value of a replication filter. Lists of label
+// names, which resources created before filters were typed may hold, are read
+// as a comma-separated list.
+type ReplicationFilterValue string
+
+func (v *ReplicationFilterValue) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		*v = ReplicationFilterValue(value)
+		return nil
+	}
+	var labels []string
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return fmt.Errorf("replication filter value must be a string or a list of strings: %w", err)
+	}
+	*v = ReplicationFilterValue(strings.Join(labels, ","))
+	return nil
+}
+
+type ReplicationTrigger struct {
+	// Type of the trigger.
+	// +kubebuilder:validation:Enum=manual;scheduled;event_based
+	Type string `json:"type"`
+
+	// Settings of 'scheduled' triggers.
+	// +optional
+	TriggerSettings *ReplicationTriggerSettings `json:"trigger_settings,omitempty"`
+}
+
+// HarborCronParser parses the 6-field cron expressions Harbor uses for
+// scheduled replications.
+var HarborCronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
+type ReplicationTriggerSettings struct {
+	// Schedule in Harbor's 6-field cron format: seconds, minutes, hours,
+	// day of month, month and day of week, e.g. '0 0 3 * * *'.
+	// +kubebuilder:validation:Pattern=`^\S+( \S+){5}$`
+	Cron string `json:"cron"`
 }
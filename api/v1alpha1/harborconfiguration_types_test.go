@@ -0,0 +1,49 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestReplicationFilterValueUnmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    ReplicationFilterValue
+		wantErr bool
+	}{
+		{name: "string", data: `"library/**"`, want: "library/**"},
+		{name: "label list", data: `["release", "stable"]`, want: "release,stable"},
+		{name: "single label", data: `["release"]`, want: "release"},
+		{name: "number", data: `1`, wantErr: true},
+		{name: "list of numbers", data: `[1, 2]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var filter ReplicationFilter
+			err := json.Unmarshal([]byte(`{"type":"label","value":`+tt.data+`}`), &filter)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && filter.Value != tt.want {
+				t.Errorf("Value = %q, want %q", filter.Value, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,426 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var harborconfigurationlog = logf.Log.WithName("harborconfiguration-resource")
+
+// maxHarborNameLength is the longest registry, project or replication rule
+// name Harbor accepts.
+const maxHarborNameLength = 255
+
+// projectNamePattern is the naming rule Harbor applies to project names.
+var projectNamePattern = regexp.MustCompile(`^[a-z0-9]+(?:[._-][a-z0-9]+)*$`)
+
+// registryProviders are the registry adapter types known to Harbor.
+var registryProviders = []string{
+	"ali-acr",
+	"artifact-hub",
+	"aws-ecr",
+	"azure-acr",
+	"docker-hub",
+	"docker-registry",
+	"dtr",
+	"github-ghcr",
+	"gitlab",
+	"google-gcr",
+	"harbor",
+	"helm-hub",
+	"huawei-SWR",
+	"jfrog-artifactory",
+	"quay",
+	"tencent-tcr",
+}
+
+// defaultRegistryDescription is the description of registries that do not
+// set one.
+const defaultRegistryDescription = "Managed by harbor-config-operator"
+
+// registryLookupTimeout bounds the registry lookups of a validation, so that
+// an unreachable Harbor does not hold up writes until the webhook times out.
+const registryLookupTimeout = 3 * time.Second
+
+// RegistryLookup reports whether a registry exists in the Harbor instance the
+// target of a resource in namespace points at.
+// +kubebuilder:object:generate=false
+type RegistryLookup func(ctx context.Context, namespace string, target HarborTarget, name string) (bool, error)
+
+// harborConfigurationValidator validates HarborConfigurations, looking up
+// registries that are referenced but not declared in Harbor.
+// +kubebuilder:object:generate=false
+type harborConfigurationValidator struct {
+	registryExists RegistryLookup
+	// instances reads the HarborInstances targets refer to.
+	instances client.Reader
+}
+
+// harborConfigurationDefaulter fills in the fields HarborConfigurations
+// commonly leave empty.
+// +kubebuilder:object:generate=false
+type harborConfigurationDefaulter struct {
+	defaultTarget HarborTarget
+}
+
+// SetupWebhookWithManager registers the HarborConfiguration webhooks. Empty
+// harborTarget fields are defaulted from defaultTarget.
+func (r *HarborConfiguration) SetupWebhookWithManager(mgr ctrl.Manager, defaultTarget HarborTarget, registryExists RegistryLookup) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithDefaulter(&harborConfigurationDefaulter{defaultTarget: defaultTarget}).
+		WithValidator(&harborConfigurationValidator{registryExists: registryExists, instances: mgr.GetClient()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-administration-harbor-configuration-v1alpha1-harborconfiguration,mutating=true,failurePolicy=fail,sideEffects=None,groups=administration.harbor.configuration,resources=harborconfigurations,verbs=create;update,versions=v1alpha1,name=mharborconfiguration.kb.io,admissionReviewVersions=v1,timeoutSeconds=5
+
+var _ webhook.CustomDefaulter = &harborConfigurationDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the type
+func (d *harborConfigurationDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	harborConfiguration := obj.(*HarborConfiguration)
+	harborconfigurationlog.Info("default", "name", harborConfiguration.Name)
+
+	spec := &harborConfiguration.Spec
+	if target := &spec.HarborTarget; target.InstanceRef == "" && target.URL == "" {
+		if target.Name == "" {
+			target.Name = d.defaultTarget.Name
+		}
+		if target.Namespace == "" {
+			target.Namespace = d.defaultTarget.Namespace
+		}
+	}
+	if spec.HarborTarget.HarborUsername == "" {
+		spec.HarborTarget.HarborUsername = d.defaultTarget.HarborUsername
+	}
+
+	if spec.Registry != nil && spec.Registry.Description == "" {
+		spec.Registry.Description = defaultRegistryDescription
+	}
+	for i := range spec.Registries {
+		if spec.Registries[i].Description == "" {
+			spec.Registries[i].Description = defaultRegistryDescription
+		}
+	}
+
+	unlimitedStorage := int64(-1)
+	if spec.ProjectReq != nil && spec.ProjectReq.StorageQuota == nil {
+		spec.ProjectReq.StorageQuota = &unlimitedStorage
+	}
+	for i := range spec.Projects {
+		if spec.Projects[i].StorageQuota == nil {
+			spec.Projects[i].StorageQuota = &unlimitedStorage
+		}
+	}
+
+	// Replications default to the project of the HarborConfiguration when it
+	// declares exactly one.
+	var destinationNamespace string
+	if projects := spec.AllProjects(); len(projects) == 1 {
+		destinationNamespace = projects[0].ProjectName
+	}
+	if spec.Replication != nil && spec.Replication.DestinationNamespace == "" {
+		spec.Replication.DestinationNamespace = destinationNamespace
+	}
+	for i := range spec.Replications {
+		if spec.Replications[i].DestinationNamespace == "" {
+			spec.Replications[i].DestinationNamespace = destinationNamespace
+		}
+	}
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-administration-harbor-configuration-v1alpha1-harborconfiguration,mutating=false,failurePolicy=fail,sideEffects=None,groups=administration.harbor.configuration,resources=harborconfigurations,verbs=create;update,versions=v1alpha1,name=vharborconfiguration.kb.io,admissionReviewVersions=v1,timeoutSeconds=5
+
+var _ webhook.CustomValidator = &harborConfigurationValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *harborConfigurationValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	harborConfiguration := obj.(*HarborConfiguration)
+	harborconfigurationlog.Info("validate create", "name", harborConfiguration.Name)
+
+	return v.validate(ctx, harborConfiguration)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *harborConfigurationValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	harborConfiguration := newObj.(*HarborConfiguration)
+	harborconfigurationlog.Info("validate update", "name", harborConfiguration.Name)
+
+	// Resources being deleted or only changing metadata, e.g. when the
+	// finalizer is removed, must not be held up by the validation.
+	if !harborConfiguration.DeletionTimestamp.IsZero() || equality.Semantic.DeepEqual(oldObj.(*HarborConfiguration).Spec, harborConfiguration.Spec) {
+		return nil
+	}
+	return v.validate(ctx, harborConfiguration)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *harborConfigurationValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+func (v *harborConfigurationValidator) validate(ctx context.Context, harborConfiguration *HarborConfiguration) error {
+	var allErrs field.ErrorList
+	spec := harborConfiguration.Spec
+	specPath := field.NewPath("spec")
+
+	allErrs = append(allErrs, validateHarborTarget(spec.HarborTarget, specPath.Child("harborTarget"))...)
+	allErrs = append(allErrs, v.validateHarborInstance(ctx, harborConfiguration.Namespace, spec.HarborTarget.InstanceRef, specPath.Child("harborTarget", "instanceRef"))...)
+
+	declaredRegistries := make(map[string]bool)
+	validateRegistry := func(registry Registry, path *field.Path) {
+		allErrs = append(allErrs, validateHarborName(registry.Name, path.Child("name"))...)
+		if declaredRegistries[registry.Name] {
+			allErrs = append(allErrs, field.Duplicate(path.Child("name"), registry.Name))
+		}
+		declaredRegistries[registry.Name] = true
+		allErrs = append(allErrs, validateRegistryProvider(registry.Provider, path.Child("provider"))...)
+	}
+	if spec.Registry != nil {
+		validateRegistry(*spec.Registry, specPath.Child("registry"))
+	}
+	for i, registry := range spec.Registries {
+		validateRegistry(registry, specPath.Child("registries").Index(i))
+	}
+
+	// Referenced registries that are not declared must already exist in Harbor.
+	lookupCtx, cancel := context.WithTimeout(ctx, registryLookupTimeout)
+	defer cancel()
+	validateRegistryRef := func(name string, path *field.Path) {
+		if declaredRegistries[name] || v.registryExists == nil {
+			return
+		}
+		exists, err := v.registryExists(lookupCtx, harborConfiguration.Namespace, spec.HarborTarget, name)
+		if err != nil {
+			// The reconciliation reports the registry as missing if Harbor
+			// cannot be reached now.
+			harborconfigurationlog.Error(err, "unable to look up registry", "name", harborConfiguration.Name, "registry", name)
+			return
+		}
+		if !exists {
+			allErrs = append(allErrs, field.NotFound(path, name))
+		}
+	}
+
+	declaredProjects := make(map[string]bool)
+	validateProject := func(project ProjectReq, path *field.Path) {
+		allErrs = append(allErrs, validateProjectName(project.ProjectName, path.Child("projectName"))...)
+		if declaredProjects[project.ProjectName] {
+			allErrs = append(allErrs, field.Duplicate(path.Child("projectName"), project.ProjectName))
+		}
+		declaredProjects[project.ProjectName] = true
+		allErrs = append(allErrs, validateStorageQuota(project.StorageQuota, path.Child("storageQuota"))...)
+		allErrs = append(allErrs, validateProjectMembers(project.Members, path.Child("members"))...)
+		if project.ProxyCacheRegistryName != "" {
+			validateRegistryRef(project.ProxyCacheRegistryName, path.Child("proxyCacheRegistryName"))
+		}
+	}
+	if spec.ProjectReq != nil {
+		validateProject(*spec.ProjectReq, specPath.Child("projectReq"))
+	}
+	for i, project := range spec.Projects {
+		validateProject(project, specPath.Child("projects").Index(i))
+	}
+
+	declaredReplications := make(map[string]bool)
+	validateReplication := func(replication Replication, path *field.Path) {
+		allErrs = append(allErrs, validateHarborName(replication.Name, path.Child("name"))...)
+		if declaredReplications[replication.Name] {
+			allErrs = append(allErrs, field.Duplicate(path.Child("name"), replication.Name))
+		}
+		declaredReplications[replication.Name] = true
+		if replication.RegistryName == "" {
+			allErrs = append(allErrs, field.Required(path.Child("registryName"), ""))
+		} else {
+			validateRegistryRef(replication.RegistryName, path.Child("registryName"))
+		}
+		allErrs = append(allErrs, validateReplicationFilters(replication.Filters, path.Child("filters"))...)
+		allErrs = append(allErrs, validateReplicationTrigger(replication.TriggerMode, path.Child("triggerMode"))...)
+	}
+	if spec.Replication != nil {
+		validateReplication(*spec.Replication, specPath.Child("replication"))
+	}
+	for i, replication := range spec.Replications {
+		validateReplication(replication, specPath.Child("replications").Index(i))
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("HarborConfiguration").GroupKind(), harborConfiguration.Name, allErrs)
+}
+
+// validateHarborTarget checks that the target names a single Harbor instance
+// and that a URL comes with credentials.
+func validateHarborTarget(target HarborTarget, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	var set []string
+	if target.URL != "" {
+		set = append(set, "url")
+	}
+	if target.InstanceRef != "" {
+		set = append(set, "instanceRef")
+	}
+	if target.Name != "" {
+		set = append(set, "name")
+	}
+	if len(set) > 1 {
+		allErrs = append(allErrs, field.Invalid(path, strings.Join(set, ", "), "only one of url, instanceRef and name may be set"))
+	}
+	if target.URL != "" && target.CredentialsSecretRef == nil {
+		allErrs = append(allErrs, field.Required(path.Child("credentialsSecretRef"), "credentials are required with url"))
+	}
+	return allErrs
+}
+
+// validateHarborInstance checks that the HarborInstance a target refers to
+// allows resources in namespace to target it.
+func (v *harborConfigurationValidator) validateHarborInstance(ctx context.Context, namespace, name string, path *field.Path) field.ErrorList {
+	if name == "" || v.instances == nil {
+		return nil
+	}
+
+	var instance HarborInstance
+	err := v.instances.Get(ctx, types.NamespacedName{Name: name}, &instance)
+	if apierrors.IsNotFound(err) {
+		return field.ErrorList{field.NotFound(path, name)}
+	}
+	if err != nil {
+		// The reconciliation enforces the allowed namespaces as well.
+		harborconfigurationlog.Error(err, "unable to get HarborInstance", "name", name)
+		return nil
+	}
+	if !instance.Spec.AllowsNamespace(namespace) {
+		return field.ErrorList{field.Forbidden(path, fmt.Sprintf("HarborInstance %s does not allow namespace %s", name, namespace))}
+	}
+	return nil
+}
+
+// validateHarborName checks a registry or replication rule name.
+func validateHarborName(name string, path *field.Path) field.ErrorList {
+	switch {
+	case name == "":
+		return field.ErrorList{field.Required(path, "")}
+	case len(name) > maxHarborNameLength:
+		return field.ErrorList{field.TooLong(path, name, maxHarborNameLength)}
+	}
+	return nil
+}
+
+// validateReplicationFilters checks that every filter has a value, which the
+// CRD schema cannot require as it also accepts lists of label names.
+func validateReplicationFilters(filters []ReplicationFilter, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, filter := range filters {
+		if strings.TrimSpace(string(filter.Value)) == "" {
+			allErrs = append(allErrs, field.Required(path.Index(i).Child("value"), ""))
+		}
+	}
+	return allErrs
+}
+
+// validateReplicationTrigger checks that a scheduled trigger has a schedule
+// Harbor accepts.
+func validateReplicationTrigger(trigger *ReplicationTrigger, path *field.Path) field.ErrorList {
+	if trigger == nil || trigger.Type != "scheduled" {
+		return nil
+	}
+	cronPath := path.Child("trigger_settings", "cron")
+	if trigger.TriggerSettings == nil || trigger.TriggerSettings.Cron == "" {
+		return field.ErrorList{field.Required(cronPath, "required for scheduled triggers")}
+	}
+	if _, err := HarborCronParser.Parse(trigger.TriggerSettings.Cron); err != nil {
+		return field.ErrorList{field.Invalid(cronPath, trigger.TriggerSettings.Cron, err.Error())}
+	}
+	return nil
+}
+
+// validateProjectMembers checks that every member is named, listed once and
+// can be added to a project.
+func validateProjectMembers(members []ProjectMember, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	declaredMembers := make(map[string]bool)
+	for i, member := range members {
+		memberPath := path.Index(i)
+		if member.Name == "" {
+			allErrs = append(allErrs, field.Required(memberPath.Child("name"), ""))
+		}
+		kind := member.Kind
+		if kind == "" {
+			kind = ProjectMemberKindUser
+		}
+		key := string(kind) + "/" + member.Name
+		if declaredMembers[key] {
+			allErrs = append(allErrs, field.Duplicate(memberPath.Child("name"), member.Name))
+		}
+		declaredMembers[key] = true
+		if kind == ProjectMemberKindLDAPGroup && member.LDAPGroupDN == "" {
+			allErrs = append(allErrs, field.Required(memberPath.Child("ldapGroupDN"), "required for ldapGroup members"))
+		}
+	}
+	return allErrs
+}
+
+// validateProjectName checks a project name against the naming rules of Harbor.
+func validateProjectName(name string, path *field.Path) field.ErrorList {
+	if allErrs := validateHarborName(name, path); len(allErrs) > 0 {
+		return allErrs
+	}
+	if !projectNamePattern.MatchString(name) {
+		return field.ErrorList{field.Invalid(path, name, fmt.Sprintf("must consist of lower case alphanumeric characters separated by '.', '_' or '-' (regex used for validation is '%s')", projectNamePattern))}
+	}
+	return nil
+}
+
+// validateStorageQuota checks that a storage quota is -1 (unlimited) or positive.
+func validateStorageQuota(storageQuota *int64, path *field.Path) field.ErrorList {
+	if storageQuota != nil && *storageQuota != -1 && *storageQuota <= 0 {
+		return field.ErrorList{field.Invalid(path, *storageQuota, "must be -1 for unlimited storage or a positive number of bytes")}
+	}
+	return nil
+}
+
+// validateRegistryProvider checks that a provider is a registry adapter type known to Harbor.
+func validateRegistryProvider(provider string, path *field.Path) field.ErrorList {
+	if provider == "" {
+		return field.ErrorList{field.Required(path, "")}
+	}
+	for _, known := range registryProviders {
+		if provider == known {
+			return nil
+		}
+	}
+	return field.ErrorList{field.NotSupported(path, provider, registryProviders)}
+}
@@ -0,0 +1,423 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestHarborConfigurationDefault(t *testing.T) {
+	defaulter := &harborConfigurationDefaulter{defaultTarget: HarborTarget{Name: "harbor", Namespace: "harbor-system", HarborUsername: "admin"}}
+	unlimited := int64(-1)
+	quota := int64(1024)
+
+	tests := []struct {
+		name string
+		spec HarborConfigurationSpec
+		want HarborConfigurationSpec
+	}{
+		{
+			name: "default target",
+			want: HarborConfigurationSpec{HarborTarget: HarborTarget{Name: "harbor", Namespace: "harbor-system", HarborUsername: "admin"}},
+		},
+		{
+			name: "partial target",
+			spec: HarborConfigurationSpec{HarborTarget: HarborTarget{Name: "other", HarborUsername: "operator"}},
+			want: HarborConfigurationSpec{HarborTarget: HarborTarget{Name: "other", Namespace: "harbor-system", HarborUsername: "operator"}},
+		},
+		{
+			name: "url target",
+			spec: HarborConfigurationSpec{HarborTarget: HarborTarget{URL: "https://harbor.example.com"}},
+			want: HarborConfigurationSpec{HarborTarget: HarborTarget{URL: "https://harbor.example.com", HarborUsername: "admin"}},
+		},
+		{
+			name: "instance target",
+			spec: HarborConfigurationSpec{HarborTarget: HarborTarget{InstanceRef: "production"}},
+			want: HarborConfigurationSpec{HarborTarget: HarborTarget{InstanceRef: "production", HarborUsername: "admin"}},
+		},
+		{
+			name: "registry descriptions",
+			spec: HarborConfigurationSpec{
+				HarborTarget: HarborTarget{InstanceRef: "production", HarborUsername: "admin"},
+				Registry:     &Registry{Name: "docker-hub"},
+				Registries:   []Registry{{Name: "quay"}, {Name: "gcr", Description: "Google"}},
+			},
+			want: HarborConfigurationSpec{
+				HarborTarget: HarborTarget{InstanceRef: "production", HarborUsername: "admin"},
+				Registry:     &Registry{Name: "docker-hub", Description: defaultRegistryDescription},
+				Registries:   []Registry{{Name: "quay", Description: defaultRegistryDescription}, {Name: "gcr", Description: "Google"}},
+			},
+		},
+		{
+			name: "storage quotas",
+			spec: HarborConfigurationSpec{
+				HarborTarget: HarborTarget{InstanceRef: "production", HarborUsername: "admin"},
+				ProjectReq:   &ProjectReq{ProjectName: "team"},
+				Projects:     []ProjectReq{{ProjectName: "limited", ProjectSettings: ProjectSettings{StorageQuota: &quota}}},
+			},
+			want: HarborConfigurationSpec{
+				HarborTarget: HarborTarget{InstanceRef: "production", HarborUsername: "admin"},
+				ProjectReq:   &ProjectReq{ProjectName: "team", ProjectSettings: ProjectSettings{StorageQuota: &unlimited}},
+				Projects:     []ProjectReq{{ProjectName: "limited", ProjectSettings: ProjectSettings{StorageQuota: &quota}}},
+			},
+		},
+		{
+			name: "destination namespace of the only project",
+			spec: HarborConfigurationSpec{
+				HarborTarget: HarborTarget{InstanceRef: "production", HarborUsername: "admin"},
+				ProjectReq:   &ProjectReq{ProjectName: "team", ProjectSettings: ProjectSettings{StorageQuota: &quota}},
+				Replication:  &Replication{Name: "mirror"},
+				Replications: []Replication{{Name: "other", ReplicationSettings: ReplicationSettings{DestinationNamespace: "elsewhere"}}},
+			},
+			want: HarborConfigurationSpec{
+				HarborTarget: HarborTarget{InstanceRef: "production", HarborUsername: "admin"},
+				ProjectReq:   &ProjectReq{ProjectName: "team", ProjectSettings: ProjectSettings{StorageQuota: &quota}},
+				Replication:  &Replication{Name: "mirror", ReplicationSettings: ReplicationSettings{DestinationNamespace: "team"}},
+				Replications: []Replication{{Name: "other", ReplicationSettings: ReplicationSettings{DestinationNamespace: "elsewhere"}}},
+			},
+		},
+		{
+			name: "no destination namespace with several projects",
+			spec: HarborConfigurationSpec{
+				HarborTarget: HarborTarget{InstanceRef: "production", HarborUsername: "admin"},
+				Projects: []ProjectReq{
+					{ProjectName: "a", ProjectSettings: ProjectSettings{StorageQuota: &quota}},
+					{ProjectName: "b", ProjectSettings: ProjectSettings{StorageQuota: &quota}},
+				},
+				Replication: &Replication{Name: "mirror"},
+			},
+			want: HarborConfigurationSpec{
+				HarborTarget: HarborTarget{InstanceRef: "production", HarborUsername: "admin"},
+				Projects: []ProjectReq{
+					{ProjectName: "a", ProjectSettings: ProjectSettings{StorageQuota: &quota}},
+					{ProjectName: "b", ProjectSettings: ProjectSettings{StorageQuota: &quota}},
+				},
+				Replication: &Replication{Name: "mirror"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			harborConfiguration := &HarborConfiguration{Spec: tt.spec}
+			if err := defaulter.Default(context.Background(), harborConfiguration); err != nil {
+				t.Fatalf("Default() error = %v", err)
+			}
+			if !reflect.DeepEqual(harborConfiguration.Spec, tt.want) {
+				t.Errorf("Default() spec = %+v, want %+v", harborConfiguration.Spec, tt.want)
+			}
+		})
+	}
+}
+
+func TestHarborConfigurationValidate(t *testing.T) {
+	registryExists := func(ctx context.Context, namespace string, target HarborTarget, name string) (bool, error) {
+		switch name {
+		case "existing":
+			return true, nil
+		case "unreachable":
+			return false, errors.New("connection refused")
+		}
+		return false, nil
+	}
+	validator := &harborConfigurationValidator{registryExists: registryExists}
+	target := HarborTarget{InstanceRef: "production"}
+	quota := int64(0)
+
+	tests := []struct {
+		name    string
+		spec    HarborConfigurationSpec
+		wantErr string
+	}{
+		{
+			name: "valid",
+			spec: HarborConfigurationSpec{
+				HarborTarget: target,
+				Registry:     &Registry{Name: "docker-hub", Provider: "docker-hub"},
+				ProjectReq:   &ProjectReq{ProjectName: "team", ProxyCacheRegistryName: "existing"},
+				Replications: []Replication{
+					{Name: "pull", RegistryName: "docker-hub"},
+					{Name: "push", RegistryName: "existing"},
+				},
+			},
+		},
+		{
+			name:    "several targets",
+			spec:    HarborConfigurationSpec{HarborTarget: HarborTarget{InstanceRef: "production", Name: "harbor"}},
+			wantErr: "spec.harborTarget",
+		},
+		{
+			name:    "url without credentials",
+			spec:    HarborConfigurationSpec{HarborTarget: HarborTarget{URL: "https://harbor.example.com"}},
+			wantErr: "spec.harborTarget.credentialsSecretRef",
+		},
+		{
+			name: "duplicate registry",
+			spec: HarborConfigurationSpec{
+				HarborTarget: target,
+				Registry:     &Registry{Name: "docker-hub", Provider: "docker-hub"},
+				Registries:   []Registry{{Name: "docker-hub", Provider: "docker-hub"}},
+			},
+			wantErr: "spec.registries[0].name",
+		},
+		{
+			name: "unknown provider",
+			spec: HarborConfigurationSpec{
+				HarborTarget: target,
+				Registries:   []Registry{{Name: "mine", Provider: "my-registry"}},
+			},
+			wantErr: "spec.registries[0].provider",
+		},
+		{
+			name: "missing registry",
+			spec: HarborConfigurationSpec{
+				HarborTarget: target,
+				Replication:  &Replication{Name: "pull", RegistryName: "missing"},
+			},
+			wantErr: "spec.replication.registryName",
+		},
+		{
+			name: "registry lookup failure",
+			spec: HarborConfigurationSpec{
+				HarborTarget: target,
+				Replication:  &Replication{Name: "pull", RegistryName: "unreachable"},
+			},
+		},
+		{
+			name: "replication without registry",
+			spec: HarborConfigurationSpec{
+				HarborTarget: target,
+				Replication:  &Replication{Name: "pull"},
+			},
+			wantErr: "spec.replication.registryName",
+		},
+		{
+			name: "filter without value",
+			spec: HarborConfigurationSpec{
+				HarborTarget: target,
+				Replication: &Replication{Name: "pull", RegistryName: "existing", ReplicationSettings: ReplicationSettings{
+					Filters: []ReplicationFilter{{Type: "name", Value: "library/**"}, {Type: "label"}},
+				}},
+			},
+			wantErr: "spec.replication.filters[1].value",
+		},
+		{
+			name: "scheduled trigger",
+			spec: HarborConfigurationSpec{
+				HarborTarget: target,
+				Replication: &Replication{Name: "pull", RegistryName: "existing", ReplicationSettings: ReplicationSettings{
+					TriggerMode: &ReplicationTrigger{Type: "scheduled", TriggerSettings: &ReplicationTriggerSettings{Cron: "0 0 3 * * *"}},
+				}},
+			},
+		},
+		{
+			name: "scheduled trigger without cron",
+			spec: HarborConfigurationSpec{
+				HarborTarget: target,
+				Replications: []Replication{{Name: "pull", RegistryName: "existing", ReplicationSettings: ReplicationSettings{
+					TriggerMode: &ReplicationTrigger{Type: "scheduled"},
+				}}},
+			},
+			wantErr: "spec.replications[0].triggerMode.trigger_settings.cron",
+		},
+		{
+			name: "scheduled trigger with 5-field cron",
+			spec: HarborConfigurationSpec{
+				HarborTarget: target,
+				Replication: &Replication{Name: "pull", RegistryName: "existing", ReplicationSettings: ReplicationSettings{
+					TriggerMode: &ReplicationTrigger{Type: "scheduled", TriggerSettings: &ReplicationTriggerSettings{Cron: "0 3 * * *"}},
+				}},
+			},
+			wantErr: "spec.replication.triggerMode.trigger_settings.cron",
+		},
+		{
+			name: "scheduled trigger with invalid cron",
+			spec: HarborConfigurationSpec{
+				HarborTarget: target,
+				Replication: &Replication{Name: "pull", RegistryName: "existing", ReplicationSettings: ReplicationSettings{
+					TriggerMode: &ReplicationTrigger{Type: "scheduled", TriggerSettings: &ReplicationTriggerSettings{Cron: "0 0 25 * * *"}},
+				}},
+			},
+			wantErr: "spec.replication.triggerMode.trigger_settings.cron",
+		},
+		{
+			name: "invalid project name",
+			spec: HarborConfigurationSpec{
+				HarborTarget: target,
+				Projects:     []ProjectReq{{ProjectName: "Team"}},
+			},
+			wantErr: "spec.projects[0].projectName",
+		},
+		{
+			name: "invalid storage quota",
+			spec: HarborConfigurationSpec{
+				HarborTarget: target,
+				ProjectReq:   &ProjectReq{ProjectName: "team", ProjectSettings: ProjectSettings{StorageQuota: &quota}},
+			},
+			wantErr: "spec.projectReq.storageQuota",
+		},
+		{
+			name: "duplicate member",
+			spec: HarborConfigurationSpec{
+				HarborTarget: target,
+				ProjectReq: &ProjectReq{ProjectName: "team", ProjectSettings: ProjectSettings{Members: []ProjectMember{
+					{Name: "alice", Role: "developer"},
+					{Name: "alice", Kind: ProjectMemberKindUser, Role: "guest"},
+				}}},
+			},
+			wantErr: "spec.projectReq.members[1].name",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.ValidateCreate(context.Background(), &HarborConfiguration{Spec: tt.spec})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateCreate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateCreate() error = %v, want an error on %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHarborConfigurationValidateUpdate(t *testing.T) {
+	validator := &harborConfigurationValidator{}
+	invalid := HarborConfigurationSpec{Registries: []Registry{{Name: "mine", Provider: "my-registry"}}}
+	now := metav1.Now()
+
+	tests := []struct {
+		name    string
+		old     HarborConfiguration
+		new     HarborConfiguration
+		wantErr bool
+	}{
+		{
+			name:    "changed spec",
+			new:     HarborConfiguration{Spec: invalid},
+			wantErr: true,
+		},
+		{
+			name: "scheduled trigger without cron",
+			old: HarborConfiguration{Spec: HarborConfigurationSpec{
+				Replication: &Replication{Name: "pull", RegistryName: "existing"},
+			}},
+			new: HarborConfiguration{Spec: HarborConfigurationSpec{
+				Replication: &Replication{Name: "pull", RegistryName: "existing", ReplicationSettings: ReplicationSettings{
+					TriggerMode: &ReplicationTrigger{Type: "scheduled", TriggerSettings: &ReplicationTriggerSettings{}},
+				}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "metadata only",
+			old:  HarborConfiguration{Spec: invalid},
+			new: HarborConfiguration{
+				ObjectMeta: metav1.ObjectMeta{Finalizers: []string{"harbor.configuration/finalizer"}},
+				Spec:       invalid,
+			},
+		},
+		{
+			name: "being deleted",
+			new: HarborConfiguration{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &now},
+				Spec:       invalid,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.ValidateUpdate(context.Background(), &tt.old, &tt.new)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateUpdate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHarborConfigurationValidateInstance(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	instances := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&HarborInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: "production"},
+		Spec: HarborInstanceSpec{
+			URL:                  "https://harbor.example.com",
+			CredentialsSecretRef: HarborCredentialsSecretReference{Name: "harbor-admin", Namespace: "harbor-system"},
+			AllowedNamespaces:    []string{"team-a"},
+		},
+	}).Build()
+	validator := &harborConfigurationValidator{instances: instances}
+
+	tests := []struct {
+		name      string
+		namespace string
+		instance  string
+		wantErr   string
+	}{
+		{
+			name:      "allowed namespace",
+			namespace: "team-a",
+			instance:  "production",
+		},
+		{
+			name:      "namespace of the credentials",
+			namespace: "harbor-system",
+			instance:  "production",
+		},
+		{
+			name:      "other namespace",
+			namespace: "team-b",
+			instance:  "production",
+			wantErr:   "Forbidden",
+		},
+		{
+			name:      "missing instance",
+			namespace: "team-a",
+			instance:  "staging",
+			wantErr:   "Not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.ValidateCreate(context.Background(), &HarborConfiguration{
+				ObjectMeta: metav1.ObjectMeta{Namespace: tt.namespace, Name: "config"},
+				Spec:       HarborConfigurationSpec{HarborTarget: HarborTarget{InstanceRef: tt.instance}},
+			})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateCreate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "spec.harborTarget.instanceRef") || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateCreate() error = %v, want %s on spec.harborTarget.instanceRef", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	SchemeBuilder.Register(&HarborInstance{}, &HarborInstanceList{})
+}
+
+type HarborInstanceSpec struct {
+	// URL of Harbor, e.g. https://harbor.example.com. The /api/v2.0 path is
+	// added when missing.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+
+	// CredentialsSecretRef refers to the Secret holding the Harbor credentials.
+	CredentialsSecretRef HarborCredentialsSecretReference `json:"credentialsSecretRef"`
+
+	// CABundle is a PEM encoded CA bundle used to verify the certificate of
+	// Harbor. The system roots are used when empty.
+	CABundle string `json:"caBundle,omitempty"`
+
+	// InsecureSkipVerify disables the verification of the certificate of Harbor.
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+
+	// Timeout of Harbor API calls. Defaults to 30s.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// AllowedNamespaces are the namespaces whose resources may target the
+	// HarborInstance, and thereby act with its credentials. Resources in the
+	// namespace of the credentials Secret are always allowed.
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+}
+
+// AllowsNamespace reports whether resources in namespace may target the
+// HarborInstance.
+func (s HarborInstanceSpec) AllowsNamespace(namespace string) bool {
+	if namespace == s.CredentialsSecretRef.Namespace {
+		return true
+	}
+	for _, allowed := range s.AllowedNamespaces {
+		if allowed == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+type HarborCredentialsSecretReference struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+
+	// UsernameKey is the key of the user name in the Secret. When the Secret
+	// has no such key, harborTarget.harborUsername is used.
+	// +kubebuilder:default=username
+	UsernameKey string `json:"usernameKey,omitempty"`
+
+	// PasswordKey is the key of the password in the Secret.
+	// +kubebuilder:default=password
+	PasswordKey string `json:"passwordKey,omitempty"`
+}
+
+// LocalHarborCredentialsSecretReference refers to a Secret holding Harbor
+// credentials in the namespace of the referring resource.
+type LocalHarborCredentialsSecretReference struct {
+	Name string `json:"name"`
+
+	// UsernameKey is the key of the user name in the Secret. When the Secret
+	// has no such key, harborTarget.harborUsername is used.
+	// +kubebuilder:default=username
+	UsernameKey string `json:"usernameKey,omitempty"`
+
+	// PasswordKey is the key of the password in the Secret.
+	// +kubebuilder:default=password
+	PasswordKey string `json:"passwordKey,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="URL",type="string",JSONPath=".spec.url"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// HarborInstance describes how to reach a Harbor instance, including ones not
+// deployed by harbor-operator. HarborTargets refer to it by name.
+type HarborInstance struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec HarborInstanceSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+type HarborInstanceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HarborInstance `json:"items,omitempty"`
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	SchemeBuilder.Register(&HarborProject{}, &HarborProjectList{})
+}
+
+type HarborProjectSpec struct {
+	HarborTarget HarborTarget `json:"harborTarget,omitempty"`
+	// AdoptionPolicy decides whether an existing Harbor object that is not
+	// managed by this resource is taken over.
+	// +kubebuilder:default=Never
+	AdoptionPolicy AdoptionPolicy `json:"adoptionPolicy,omitempty"`
+	// Name of the project in Harbor, defaults to the name of the HarborProject.
+	ProjectName     string `json:"projectName,omitempty"`
+	ProjectSettings `json:",inline"`
+	// Name of a HarborRegistry in the same namespace the project acts as a
+	// proxy cache for. Leave empty for a regular project.
+	ProxyCacheRegistryRef string `json:"proxyCacheRegistryRef,omitempty"`
+}
+
+type HarborProjectStatus struct {
+	// ID of the project in Harbor.
+	ID string `json:"id,omitempty"`
+
+	// ObservedGeneration is the most recent generation reconciled by the controller.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions describe the reconciliation state of the project.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+//+kubebuilder:printcolumn:name="ID",type="string",JSONPath=".status.id"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// HarborProject is a project in Harbor, optionally acting as a proxy cache
+// for a HarborRegistry.
+type HarborProject struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HarborProjectSpec   `json:"spec,omitempty"`
+	Status HarborProjectStatus `json:"status,omitempty"`
+}
+
+// HarborProjectName returns the name of the project in Harbor.
+func (p *HarborProject) HarborProjectName() string {
+	if p.Spec.ProjectName != "" {
+		return p.Spec.ProjectName
+	}
+	return p.Name
+}
+
+//+kubebuilder:object:root=true
+
+type HarborProjectList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HarborProject `json:"items,omitempty"`
+}
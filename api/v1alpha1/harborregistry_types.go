@@ -0,0 +1,81 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	SchemeBuilder.Register(&HarborRegistry{}, &HarborRegistryList{})
+}
+
+type HarborRegistrySpec struct {
+	HarborTarget HarborTarget `json:"harborTarget,omitempty"`
+	// AdoptionPolicy decides whether an existing Harbor object that is not
+	// managed by this resource is taken over.
+	// +kubebuilder:default=Never
+	AdoptionPolicy AdoptionPolicy `json:"adoptionPolicy,omitempty"`
+	// Registry configuration. The name defaults to the name of the HarborRegistry.
+	Registry `json:",inline"`
+}
+
+type HarborRegistryStatus struct {
+	// ID of the registry in Harbor.
+	ID int64 `json:"id,omitempty"`
+
+	// ObservedGeneration is the most recent generation reconciled by the controller.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions describe the reconciliation state of the registry.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+//+kubebuilder:printcolumn:name="ID",type="integer",JSONPath=".status.id"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// HarborRegistry is a registry endpoint in Harbor that HarborProjects and
+// HarborReplicationPolicies can refer to. It is only removed from Harbor once
+// nothing refers to it anymore.
+type HarborRegistry struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HarborRegistrySpec   `json:"spec,omitempty"`
+	Status HarborRegistryStatus `json:"status,omitempty"`
+}
+
+// RegistryName returns the name of the registry in Harbor.
+func (r *HarborRegistry) RegistryName() string {
+	if r.Spec.Name != "" {
+		return r.Spec.Name
+	}
+	return r.Name
+}
+
+//+kubebuilder:object:root=true
+
+type HarborRegistryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HarborRegistry `json:"items,omitempty"`
+}
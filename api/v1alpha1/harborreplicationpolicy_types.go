@@ -0,0 +1,89 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	SchemeBuilder.Register(&HarborReplicationPolicy{}, &HarborReplicationPolicyList{})
+}
+
+type HarborReplicationPolicySpec struct {
+	HarborTarget HarborTarget `json:"harborTarget,omitempty"`
+	// AdoptionPolicy decides whether an existing Harbor object that is not
+	// managed by this resource is taken over.
+	// +kubebuilder:default=Never
+	AdoptionPolicy AdoptionPolicy `json:"adoptionPolicy,omitempty"`
+	// Name of the replication rule in Harbor, defaults to the name of the HarborReplicationPolicy.
+	Name string `json:"name,omitempty"`
+	// Name of the HarborRegistry in the same namespace to replicate from.
+	RegistryRef         string `json:"registryRef"`
+	ReplicationSettings `json:",inline"`
+}
+
+type HarborReplicationPolicyStatus struct {
+	// ID of the replication rule in Harbor.
+	ID int64 `json:"id,omitempty"`
+
+	// NextScheduledTime is the next run of a scheduled trigger.
+	NextScheduledTime *metav1.Time `json:"nextScheduledTime,omitempty"`
+
+	ReplicationRunStatus `json:",inline"`
+
+	// ObservedGeneration is the most recent generation reconciled by the controller.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions describe the reconciliation state of the replication rule.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+//+kubebuilder:printcolumn:name="ID",type="integer",JSONPath=".status.id"
+//+kubebuilder:printcolumn:name="Next Run",type="date",JSONPath=".status.nextScheduledTime"
+//+kubebuilder:printcolumn:name="Last Run",type="string",JSONPath=".status.lastExecution.status"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// HarborReplicationPolicy is a replication rule in Harbor pulling from a HarborRegistry.
+type HarborReplicationPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HarborReplicationPolicySpec   `json:"spec,omitempty"`
+	Status HarborReplicationPolicyStatus `json:"status,omitempty"`
+}
+
+// PolicyName returns the name of the replication rule in Harbor.
+func (p *HarborReplicationPolicy) PolicyName() string {
+	if p.Spec.Name != "" {
+		return p.Spec.Name
+	}
+	return p.Name
+}
+
+//+kubebuilder:object:root=true
+
+type HarborReplicationPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HarborReplicationPolicy `json:"items,omitempty"`
+}
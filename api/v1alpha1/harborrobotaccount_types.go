@@ -0,0 +1,179 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	SchemeBuilder.Register(&HarborRobotAccount{}, &HarborRobotAccountList{})
+}
+
+// RobotAccountLevel is the scope of a robot account in Harbor.
+// +kubebuilder:validation:Enum=project;system
+type RobotAccountLevel string
+
+const (
+	// RobotAccountLevelProject limits the robot account to a single project.
+	RobotAccountLevelProject RobotAccountLevel = "project"
+	// RobotAccountLevelSystem allows granting the robot account permissions
+	// on any project.
+	RobotAccountLevelSystem RobotAccountLevel = "system"
+)
+
+type HarborRobotAccountSpec struct {
+	HarborTarget HarborTarget `json:"harborTarget,omitempty"`
+	// AdoptionPolicy decides whether an existing Harbor object that is not
+	// managed by this resource is taken over.
+	// +kubebuilder:default=Never
+	AdoptionPolicy AdoptionPolicy `json:"adoptionPolicy,omitempty"`
+	// Name of the robot account in Harbor without the robot$ and project
+	// prefixes, defaults to the name of the HarborRobotAccount.
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Level of the robot account. A project level account belongs to the
+	// project named in projectName.
+	// +kubebuilder:default=project
+	Level RobotAccountLevel `json:"level,omitempty"`
+	// ProjectName is the project a project level robot account belongs to.
+	ProjectName string `json:"projectName,omitempty"`
+	// Permissions granted to the robot account.
+	// +kubebuilder:validation:MinItems=1
+	Permissions []RobotPermission `json:"permissions"`
+	// Duration is how many days the credentials are valid, -1 for never.
+	// +kubebuilder:default=30
+	// +kubebuilder:validation:Minimum=-1
+	Duration int64 `json:"duration,omitempty"`
+	// RefreshBefore is how long before expiry the robot account is
+	// recreated with new credentials, at most half the duration.
+	// +kubebuilder:default="24h"
+	RefreshBefore *metav1.Duration `json:"refreshBefore,omitempty"`
+	// Disabled disables the robot account in Harbor.
+	Disabled bool `json:"disabled,omitempty"`
+	// SecretName is the kubernetes.io/dockerconfigjson Secret in the same
+	// namespace the credentials are written to, defaults to the name of the
+	// HarborRobotAccount.
+	SecretName string `json:"secretName,omitempty"`
+	// NamespaceSelector selects further namespaces the Secret is copied to,
+	// among those labelled administration.harbor.configuration/pull-secrets=true.
+	// Copies are kept in sync and removed from namespaces that stop matching.
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// PatchDefaultServiceAccount adds the Secret to the imagePullSecrets of
+	// the default ServiceAccount of every namespace holding it.
+	PatchDefaultServiceAccount bool `json:"patchDefaultServiceAccount,omitempty"`
+	// Registry is the host written to the Secret, defaults to the host of
+	// the Harbor URL, or the external URL of a HarborCluster.
+	Registry string `json:"registry,omitempty"`
+	// DeletionPolicy decides whether the robot account is deleted from Harbor
+	// with the resource.
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+// RobotPermission grants a robot account access to a project.
+type RobotPermission struct {
+	// Kind of the permission.
+	// +kubebuilder:validation:Enum=project;system
+	// +kubebuilder:default=project
+	Kind string `json:"kind,omitempty"`
+	// Namespace is the project the permission applies to, or '*' for all
+	// projects. Defaults to projectName.
+	Namespace string `json:"namespace,omitempty"`
+	// Access lists the allowed or denied actions.
+	// +kubebuilder:validation:MinItems=1
+	Access []RobotAccess `json:"access"`
+}
+
+type RobotAccess struct {
+	// Resource, such as 'repository' or 'artifact'.
+	Resource string `json:"resource"`
+	// Action, such as 'pull' or 'push'.
+	Action string `json:"action"`
+	// Effect of the access.
+	// +kubebuilder:validation:Enum=allow;deny
+	// +kubebuilder:default=allow
+	Effect string `json:"effect,omitempty"`
+}
+
+type HarborRobotAccountStatus struct {
+	// ID of the robot account in Harbor.
+	ID int64 `json:"id,omitempty"`
+
+	// RobotName is the full name of the robot account in Harbor, e.g.
+	// robot$project+name.
+	RobotName string `json:"robotName,omitempty"`
+
+	// ExpiresAt is when the credentials expire, unset when they never do.
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// SecretName is the Secret holding the credentials.
+	SecretName string `json:"secretName,omitempty"`
+
+	// Namespaces holding a copy of the Secret.
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// ObservedGeneration is the most recent generation reconciled by the controller.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions describe the reconciliation state of the robot account.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+//+kubebuilder:printcolumn:name="Robot",type="string",JSONPath=".status.robotName"
+//+kubebuilder:printcolumn:name="Secret",type="string",JSONPath=".status.secretName"
+//+kubebuilder:printcolumn:name="Expires",type="date",JSONPath=".status.expiresAt"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// HarborRobotAccount is a robot account in Harbor whose credentials are kept
+// in a kubernetes.io/dockerconfigjson Secret.
+type HarborRobotAccount struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HarborRobotAccountSpec   `json:"spec,omitempty"`
+	Status HarborRobotAccountStatus `json:"status,omitempty"`
+}
+
+// RobotAccountName returns the name of the robot account in Harbor without
+// the robot$ and project prefixes.
+func (a *HarborRobotAccount) RobotAccountName() string {
+	if a.Spec.Name != "" {
+		return a.Spec.Name
+	}
+	return a.Name
+}
+
+// CredentialsSecretName returns the name of the Secret holding the credentials.
+func (a *HarborRobotAccount) CredentialsSecretName() string {
+	if a.Spec.SecretName != "" {
+		return a.Spec.SecretName
+	}
+	return a.Name
+}
+
+//+kubebuilder:object:root=true
+
+type HarborRobotAccountList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HarborRobotAccount `json:"items,omitempty"`
+}
@@ -22,17 +22,48 @@ limitations under the License.
 package v1alpha1
 
 import (
-	v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CABundleRef) DeepCopyInto(out *CABundleRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CABundleRef.
+func (in *CABundleRef) DeepCopy() *CABundleRef {
+	if in == nil {
+		return nil
+	}
+	out := new(CABundleRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CredentialSecretRef) DeepCopyInto(out *CredentialSecretRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CredentialSecretRef.
+func (in *CredentialSecretRef) DeepCopy() *CredentialSecretRef {
+	if in == nil {
+		return nil
+	}
+	out := new(CredentialSecretRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HarborConfiguration) DeepCopyInto(out *HarborConfiguration) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborConfiguration.
@@ -88,10 +119,43 @@ func (in *HarborConfigurationList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HarborConfigurationSpec) DeepCopyInto(out *HarborConfigurationSpec) {
 	*out = *in
-	out.HarborTarget = in.HarborTarget
-	in.Registry.DeepCopyInto(&out.Registry)
-	in.ProjectReq.DeepCopyInto(&out.ProjectReq)
-	in.Replication.DeepCopyInto(&out.Replication)
+	in.HarborTarget.DeepCopyInto(&out.HarborTarget)
+	if in.Registry != nil {
+		in, out := &in.Registry, &out.Registry
+		*out = new(Registry)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProjectReq != nil {
+		in, out := &in.ProjectReq, &out.ProjectReq
+		*out = new(ProjectReq)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Replication != nil {
+		in, out := &in.Replication, &out.Replication
+		*out = new(Replication)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Registries != nil {
+		in, out := &in.Registries, &out.Registries
+		*out = make([]Registry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Projects != nil {
+		in, out := &in.Projects, &out.Projects
+		*out = make([]ProjectReq, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Replications != nil {
+		in, out := &in.Replications, &out.Replications
+		*out = make([]Replication, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborConfigurationSpec.
@@ -107,6 +171,30 @@ func (in *HarborConfigurationSpec) DeepCopy() *HarborConfigurationSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HarborConfigurationStatus) DeepCopyInto(out *HarborConfigurationStatus) {
 	*out = *in
+	if in.Registries != nil {
+		in, out := &in.Registries, &out.Registries
+		*out = make([]RegistryStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Projects != nil {
+		in, out := &in.Projects, &out.Projects
+		*out = make([]ProjectStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Replications != nil {
+		in, out := &in.Replications, &out.Replications
+		*out = make([]ReplicationStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborConfigurationStatus.
@@ -120,108 +208,972 @@ func (in *HarborConfigurationStatus) DeepCopy() *HarborConfigurationStatus {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HarborTarget) DeepCopyInto(out *HarborTarget) {
+func (in *HarborCredentialsSecretReference) DeepCopyInto(out *HarborCredentialsSecretReference) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborTarget.
-func (in *HarborTarget) DeepCopy() *HarborTarget {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborCredentialsSecretReference.
+func (in *HarborCredentialsSecretReference) DeepCopy() *HarborCredentialsSecretReference {
 	if in == nil {
 		return nil
 	}
-	out := new(HarborTarget)
+	out := new(HarborCredentialsSecretReference)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProjectReq) DeepCopyInto(out *ProjectReq) {
+func (in *HarborInstance) DeepCopyInto(out *HarborInstance) {
 	*out = *in
-	if in.StorageQuota != nil {
-		in, out := &in.StorageQuota, &out.StorageQuota
-		*out = new(int64)
-		**out = **in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborInstance.
+func (in *HarborInstance) DeepCopy() *HarborInstance {
+	if in == nil {
+		return nil
 	}
-	if in.Public != nil {
-		in, out := &in.Public, &out.Public
-		*out = new(bool)
-		**out = **in
+	out := new(HarborInstance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HarborInstance) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
 	}
+	return nil
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectReq.
-func (in *ProjectReq) DeepCopy() *ProjectReq {
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HarborInstanceList) DeepCopyInto(out *HarborInstanceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HarborInstance, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborInstanceList.
+func (in *HarborInstanceList) DeepCopy() *HarborInstanceList {
 	if in == nil {
 		return nil
 	}
-	out := new(ProjectReq)
+	out := new(HarborInstanceList)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HarborInstanceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Registry) DeepCopyInto(out *Registry) {
+func (in *HarborInstanceSpec) DeepCopyInto(out *HarborInstanceSpec) {
 	*out = *in
-	if in.Credential != nil {
-		in, out := &in.Credential, &out.Credential
-		*out = new(RegistryCredential)
+	out.CredentialsSecretRef = in.CredentialsSecretRef
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
 		**out = **in
 	}
+	if in.AllowedNamespaces != nil {
+		in, out := &in.AllowedNamespaces, &out.AllowedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Registry.
-func (in *Registry) DeepCopy() *Registry {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborInstanceSpec.
+func (in *HarborInstanceSpec) DeepCopy() *HarborInstanceSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(Registry)
+	out := new(HarborInstanceSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RegistryCredential) DeepCopyInto(out *RegistryCredential) {
+func (in *HarborProject) DeepCopyInto(out *HarborProject) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryCredential.
-func (in *RegistryCredential) DeepCopy() *RegistryCredential {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborProject.
+func (in *HarborProject) DeepCopy() *HarborProject {
 	if in == nil {
 		return nil
 	}
-	out := new(RegistryCredential)
+	out := new(HarborProject)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HarborProject) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Replication) DeepCopyInto(out *Replication) {
+func (in *HarborProjectList) DeepCopyInto(out *HarborProjectList) {
 	*out = *in
-	if in.DestinationRegistry != nil {
-		in, out := &in.DestinationRegistry, &out.DestinationRegistry
-		*out = new(v1.JSON)
-		(*in).DeepCopyInto(*out)
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HarborProject, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
-	if in.Filters != nil {
-		in, out := &in.Filters, &out.Filters
-		*out = make([]v1.JSON, len(*in))
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborProjectList.
+func (in *HarborProjectList) DeepCopy() *HarborProjectList {
+	if in == nil {
+		return nil
+	}
+	out := new(HarborProjectList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HarborProjectList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HarborProjectSpec) DeepCopyInto(out *HarborProjectSpec) {
+	*out = *in
+	in.HarborTarget.DeepCopyInto(&out.HarborTarget)
+	in.ProjectSettings.DeepCopyInto(&out.ProjectSettings)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborProjectSpec.
+func (in *HarborProjectSpec) DeepCopy() *HarborProjectSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HarborProjectSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HarborProjectStatus) DeepCopyInto(out *HarborProjectStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	if in.TriggerMode != nil {
-		in, out := &in.TriggerMode, &out.TriggerMode
-		*out = new(v1.JSON)
-		(*in).DeepCopyInto(*out)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborProjectStatus.
+func (in *HarborProjectStatus) DeepCopy() *HarborProjectStatus {
+	if in == nil {
+		return nil
 	}
+	out := new(HarborProjectStatus)
+	in.DeepCopyInto(out)
+	return out
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Replication.
-func (in *Replication) DeepCopy() *Replication {
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HarborRegistry) DeepCopyInto(out *HarborRegistry) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborRegistry.
+func (in *HarborRegistry) DeepCopy() *HarborRegistry {
 	if in == nil {
 		return nil
 	}
-	out := new(Replication)
+	out := new(HarborRegistry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HarborRegistry) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HarborRegistryList) DeepCopyInto(out *HarborRegistryList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HarborRegistry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborRegistryList.
+func (in *HarborRegistryList) DeepCopy() *HarborRegistryList {
+	if in == nil {
+		return nil
+	}
+	out := new(HarborRegistryList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HarborRegistryList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HarborRegistrySpec) DeepCopyInto(out *HarborRegistrySpec) {
+	*out = *in
+	in.HarborTarget.DeepCopyInto(&out.HarborTarget)
+	in.Registry.DeepCopyInto(&out.Registry)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborRegistrySpec.
+func (in *HarborRegistrySpec) DeepCopy() *HarborRegistrySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HarborRegistrySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HarborRegistryStatus) DeepCopyInto(out *HarborRegistryStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborRegistryStatus.
+func (in *HarborRegistryStatus) DeepCopy() *HarborRegistryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HarborRegistryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HarborReplicationPolicy) DeepCopyInto(out *HarborReplicationPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborReplicationPolicy.
+func (in *HarborReplicationPolicy) DeepCopy() *HarborReplicationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(HarborReplicationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HarborReplicationPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HarborReplicationPolicyList) DeepCopyInto(out *HarborReplicationPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HarborReplicationPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborReplicationPolicyList.
+func (in *HarborReplicationPolicyList) DeepCopy() *HarborReplicationPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(HarborReplicationPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HarborReplicationPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HarborReplicationPolicySpec) DeepCopyInto(out *HarborReplicationPolicySpec) {
+	*out = *in
+	in.HarborTarget.DeepCopyInto(&out.HarborTarget)
+	in.ReplicationSettings.DeepCopyInto(&out.ReplicationSettings)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborReplicationPolicySpec.
+func (in *HarborReplicationPolicySpec) DeepCopy() *HarborReplicationPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HarborReplicationPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HarborReplicationPolicyStatus) DeepCopyInto(out *HarborReplicationPolicyStatus) {
+	*out = *in
+	if in.NextScheduledTime != nil {
+		in, out := &in.NextScheduledTime, &out.NextScheduledTime
+		*out = (*in).DeepCopy()
+	}
+	in.ReplicationRunStatus.DeepCopyInto(&out.ReplicationRunStatus)
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborReplicationPolicyStatus.
+func (in *HarborReplicationPolicyStatus) DeepCopy() *HarborReplicationPolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HarborReplicationPolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HarborRobotAccount) DeepCopyInto(out *HarborRobotAccount) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborRobotAccount.
+func (in *HarborRobotAccount) DeepCopy() *HarborRobotAccount {
+	if in == nil {
+		return nil
+	}
+	out := new(HarborRobotAccount)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HarborRobotAccount) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HarborRobotAccountList) DeepCopyInto(out *HarborRobotAccountList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HarborRobotAccount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborRobotAccountList.
+func (in *HarborRobotAccountList) DeepCopy() *HarborRobotAccountList {
+	if in == nil {
+		return nil
+	}
+	out := new(HarborRobotAccountList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HarborRobotAccountList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HarborRobotAccountSpec) DeepCopyInto(out *HarborRobotAccountSpec) {
+	*out = *in
+	in.HarborTarget.DeepCopyInto(&out.HarborTarget)
+	if in.Permissions != nil {
+		in, out := &in.Permissions, &out.Permissions
+		*out = make([]RobotPermission, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RefreshBefore != nil {
+		in, out := &in.RefreshBefore, &out.RefreshBefore
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborRobotAccountSpec.
+func (in *HarborRobotAccountSpec) DeepCopy() *HarborRobotAccountSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HarborRobotAccountSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HarborRobotAccountStatus) DeepCopyInto(out *HarborRobotAccountStatus) {
+	*out = *in
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborRobotAccountStatus.
+func (in *HarborRobotAccountStatus) DeepCopy() *HarborRobotAccountStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HarborRobotAccountStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HarborTLS) DeepCopyInto(out *HarborTLS) {
+	*out = *in
+	if in.CARef != nil {
+		in, out := &in.CARef, &out.CARef
+		*out = new(CABundleRef)
+		**out = **in
+	}
+	if in.ClientCertificateSecretRef != nil {
+		in, out := &in.ClientCertificateSecretRef, &out.ClientCertificateSecretRef
+		*out = new(TLSSecretRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborTLS.
+func (in *HarborTLS) DeepCopy() *HarborTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(HarborTLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HarborTarget) DeepCopyInto(out *HarborTarget) {
+	*out = *in
+	if in.CredentialsSecretRef != nil {
+		in, out := &in.CredentialsSecretRef, &out.CredentialsSecretRef
+		*out = new(LocalHarborCredentialsSecretReference)
+		**out = **in
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(HarborTLS)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborTarget.
+func (in *HarborTarget) DeepCopy() *HarborTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(HarborTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LocalHarborCredentialsSecretReference) DeepCopyInto(out *LocalHarborCredentialsSecretReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocalHarborCredentialsSecretReference.
+func (in *LocalHarborCredentialsSecretReference) DeepCopy() *LocalHarborCredentialsSecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(LocalHarborCredentialsSecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectMember) DeepCopyInto(out *ProjectMember) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectMember.
+func (in *ProjectMember) DeepCopy() *ProjectMember {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectMember)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectMetadata) DeepCopyInto(out *ProjectMetadata) {
+	*out = *in
+	if in.AutoScan != nil {
+		in, out := &in.AutoScan, &out.AutoScan
+		*out = new(bool)
+		**out = **in
+	}
+	if in.EnableContentTrust != nil {
+		in, out := &in.EnableContentTrust, &out.EnableContentTrust
+		*out = new(bool)
+		**out = **in
+	}
+	if in.EnableContentTrustCosign != nil {
+		in, out := &in.EnableContentTrustCosign, &out.EnableContentTrustCosign
+		*out = new(bool)
+		**out = **in
+	}
+	if in.PreventVul != nil {
+		in, out := &in.PreventVul, &out.PreventVul
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ReuseSysCVEAllowlist != nil {
+		in, out := &in.ReuseSysCVEAllowlist, &out.ReuseSysCVEAllowlist
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectMetadata.
+func (in *ProjectMetadata) DeepCopy() *ProjectMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectReq) DeepCopyInto(out *ProjectReq) {
+	*out = *in
+	in.ProjectSettings.DeepCopyInto(&out.ProjectSettings)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectReq.
+func (in *ProjectReq) DeepCopy() *ProjectReq {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectReq)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectSettings) DeepCopyInto(out *ProjectSettings) {
+	*out = *in
+	if in.StorageQuota != nil {
+		in, out := &in.StorageQuota, &out.StorageQuota
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Public != nil {
+		in, out := &in.Public, &out.Public
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = new(ProjectMetadata)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Members != nil {
+		in, out := &in.Members, &out.Members
+		*out = make([]ProjectMember, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectSettings.
+func (in *ProjectSettings) DeepCopy() *ProjectSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectStatus) DeepCopyInto(out *ProjectStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectStatus.
+func (in *ProjectStatus) DeepCopy() *ProjectStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Registry) DeepCopyInto(out *Registry) {
+	*out = *in
+	if in.Credential != nil {
+		in, out := &in.Credential, &out.Credential
+		*out = new(RegistryCredential)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Registry.
+func (in *Registry) DeepCopy() *Registry {
+	if in == nil {
+		return nil
+	}
+	out := new(Registry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryCredential) DeepCopyInto(out *RegistryCredential) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(CredentialSecretRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryCredential.
+func (in *RegistryCredential) DeepCopy() *RegistryCredential {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryCredential)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryStatus) DeepCopyInto(out *RegistryStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryStatus.
+func (in *RegistryStatus) DeepCopy() *RegistryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Replication) DeepCopyInto(out *Replication) {
+	*out = *in
+	in.ReplicationSettings.DeepCopyInto(&out.ReplicationSettings)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Replication.
+func (in *Replication) DeepCopy() *Replication {
+	if in == nil {
+		return nil
+	}
+	out := new(Replication)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicationExecutionStatus) DeepCopyInto(out *ReplicationExecutionStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.EndTime != nil {
+		in, out := &in.EndTime, &out.EndTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicationExecutionStatus.
+func (in *ReplicationExecutionStatus) DeepCopy() *ReplicationExecutionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicationExecutionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicationFilter) DeepCopyInto(out *ReplicationFilter) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicationFilter.
+func (in *ReplicationFilter) DeepCopy() *ReplicationFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicationFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicationRunStatus) DeepCopyInto(out *ReplicationRunStatus) {
+	*out = *in
+	if in.LastExecution != nil {
+		in, out := &in.LastExecution, &out.LastExecution
+		*out = new(ReplicationExecutionStatus)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicationRunStatus.
+func (in *ReplicationRunStatus) DeepCopy() *ReplicationRunStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicationRunStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicationSettings) DeepCopyInto(out *ReplicationSettings) {
+	*out = *in
+	if in.DestinationRegistry != nil {
+		in, out := &in.DestinationRegistry, &out.DestinationRegistry
+		*out = new(apiextensionsv1.JSON)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Filters != nil {
+		in, out := &in.Filters, &out.Filters
+		*out = make([]ReplicationFilter, len(*in))
+		copy(*out, *in)
+	}
+	if in.TriggerMode != nil {
+		in, out := &in.TriggerMode, &out.TriggerMode
+		*out = new(ReplicationTrigger)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicationSettings.
+func (in *ReplicationSettings) DeepCopy() *ReplicationSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicationSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicationStatus) DeepCopyInto(out *ReplicationStatus) {
+	*out = *in
+	if in.NextScheduledTime != nil {
+		in, out := &in.NextScheduledTime, &out.NextScheduledTime
+		*out = (*in).DeepCopy()
+	}
+	in.ReplicationRunStatus.DeepCopyInto(&out.ReplicationRunStatus)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicationStatus.
+func (in *ReplicationStatus) DeepCopy() *ReplicationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicationTrigger) DeepCopyInto(out *ReplicationTrigger) {
+	*out = *in
+	if in.TriggerSettings != nil {
+		in, out := &in.TriggerSettings, &out.TriggerSettings
+		*out = new(ReplicationTriggerSettings)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicationTrigger.
+func (in *ReplicationTrigger) DeepCopy() *ReplicationTrigger {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicationTrigger)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicationTriggerSettings) DeepCopyInto(out *ReplicationTriggerSettings) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicationTriggerSettings.
+func (in *ReplicationTriggerSettings) DeepCopy() *ReplicationTriggerSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicationTriggerSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RobotAccess) DeepCopyInto(out *RobotAccess) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RobotAccess.
+func (in *RobotAccess) DeepCopy() *RobotAccess {
+	if in == nil {
+		return nil
+	}
+	out := new(RobotAccess)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RobotPermission) DeepCopyInto(out *RobotPermission) {
+	*out = *in
+	if in.Access != nil {
+		in, out := &in.Access, &out.Access
+		*out = make([]RobotAccess, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RobotPermission.
+func (in *RobotPermission) DeepCopy() *RobotPermission {
+	if in == nil {
+		return nil
+	}
+	out := new(RobotPermission)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSSecretRef) DeepCopyInto(out *TLSSecretRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSSecretRef.
+func (in *TLSSecretRef) DeepCopy() *TLSSecretRef {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSSecretRef)
 	in.DeepCopyInto(out)
 	return out
 }
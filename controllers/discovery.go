@@ -0,0 +1,83 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"sync"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// preferredVersion caches the version of a resource the API server prefers.
+type preferredVersion struct {
+	mu      sync.Mutex
+	version string
+}
+
+// get returns resource in the version the API server prefers, looking it up
+// through discovery on first use.
+func (p *preferredVersion) get(discoveryClient discovery.DiscoveryInterface, resource schema.GroupResource) (schema.GroupVersionResource, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.version == "" {
+		version, err := discoverPreferredVersion(discoveryClient, resource)
+		if err != nil {
+			return schema.GroupVersionResource{}, err
+		}
+		p.version = version
+	}
+	return resource.WithVersion(p.version), nil
+}
+
+// forget drops the cached version, e.g. after an upgrade stopped serving it.
+func (p *preferredVersion) forget() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.version = ""
+}
+
+// discoverPreferredVersion returns the preferred version of the group of
+// resource, or the first other version serving resource.
+func discoverPreferredVersion(discoveryClient discovery.DiscoveryInterface, resource schema.GroupResource) (string, error) {
+	groups, err := discoveryClient.ServerGroups()
+	if err != nil {
+		return "", err
+	}
+
+	for _, group := range groups.Groups {
+		if group.Name != resource.Group {
+			continue
+		}
+		versions := append([]v1.GroupVersionForDiscovery{group.PreferredVersion}, group.Versions...)
+		for _, version := range versions {
+			resources, err := discoveryClient.ServerResourcesForGroupVersion(version.GroupVersion)
+			if err != nil {
+				return "", err
+			}
+			for _, apiResource := range resources.APIResources {
+				if apiResource.Name == resource.Resource {
+					return version.Version, nil
+				}
+			}
+		}
+	}
+	return "", fmt.Errorf("the API server does not serve %s", resource)
+}
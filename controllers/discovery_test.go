@@ -0,0 +1,120 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// newFakeDiscovery serves resources, preferring the first version listed for
+// each group.
+func newFakeDiscovery(resources ...*metav1.APIResourceList) *fakediscovery.FakeDiscovery {
+	return &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: resources}}
+}
+
+func harborClusterResourceList(groupVersion string, resources ...string) *metav1.APIResourceList {
+	list := &metav1.APIResourceList{GroupVersion: groupVersion}
+	for _, resource := range resources {
+		list.APIResources = append(list.APIResources, metav1.APIResource{Name: resource})
+	}
+	return list
+}
+
+func TestDiscoverPreferredVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources []*metav1.APIResourceList
+		want      string
+		wantErr   bool
+	}{
+		{
+			name: "preferred version",
+			resources: []*metav1.APIResourceList{
+				harborClusterResourceList("goharbor.io/v1beta1", "harborclusters", "harbors"),
+				harborClusterResourceList("goharbor.io/v1alpha3", "harborclusters"),
+			},
+			want: "v1beta1",
+		},
+		{
+			name: "only another version serves harborclusters",
+			resources: []*metav1.APIResourceList{
+				harborClusterResourceList("goharbor.io/v1beta1", "harbors"),
+				harborClusterResourceList("goharbor.io/v1alpha3", "harborclusters"),
+			},
+			want: "v1alpha3",
+		},
+		{
+			name: "only v1alpha3",
+			resources: []*metav1.APIResourceList{
+				harborClusterResourceList("goharbor.io/v1alpha3", "harborclusters"),
+			},
+			want: "v1alpha3",
+		},
+		{
+			name: "harborclusters not served",
+			resources: []*metav1.APIResourceList{
+				harborClusterResourceList("goharbor.io/v1beta1", "harbors"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "group not served",
+			resources: []*metav1.APIResourceList{
+				harborClusterResourceList("v1", "secrets"),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := discoverPreferredVersion(newFakeDiscovery(tt.resources...), harborClusterResource)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("discoverPreferredVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("discoverPreferredVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreferredVersion(t *testing.T) {
+	discoveryClient := newFakeDiscovery(harborClusterResourceList("goharbor.io/v1alpha3", "harborclusters"))
+	var version preferredVersion
+
+	gvr, err := version.get(discoveryClient, harborClusterResource)
+	if err != nil {
+		t.Fatalf("get() error = %v", err)
+	}
+	if gvr != harborClusterResource.WithVersion("v1alpha3") {
+		t.Errorf("get() = %v, want v1alpha3", gvr)
+	}
+
+	// harbor-operator was upgraded, which only matters once forgotten.
+	discoveryClient.Resources = []*metav1.APIResourceList{harborClusterResourceList("goharbor.io/v1beta1", "harborclusters")}
+	if gvr, _ := version.get(discoveryClient, harborClusterResource); gvr.Version != "v1alpha3" {
+		t.Errorf("get() = %v, want the cached v1alpha3", gvr)
+	}
+	version.forget()
+	if gvr, _ := version.get(discoveryClient, harborClusterResource); gvr.Version != "v1beta1" {
+		t.Errorf("get() after forget() = %v, want v1beta1", gvr)
+	}
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	modelv2 "github.com/mittwald/goharbor-client/v5/apiv2/model"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+// driftDeleted is reported when a Harbor object that had been reconciled
+// before was missing and had to be recreated.
+const driftDeleted = "deleted"
+
+// replicationPolicyDrift lists the fields of the replication rule in Harbor
+// that differ from the requested rule.
+func replicationPolicyDrift(existing, requested *modelv2.ReplicationPolicy) []string {
+	var drifted []string
+	if stripOwner(existing.Description) != stripOwner(requested.Description) {
+		drifted = append(drifted, "description")
+	}
+	if registryID(existing.SrcRegistry) != registryID(requested.SrcRegistry) {
+		drifted = append(drifted, fieldChange("registry", registryID(existing.SrcRegistry), registryID(requested.SrcRegistry)))
+	}
+	if existing.DestNamespace != requested.DestNamespace {
+		drifted = append(drifted, fieldChange("destinationNamespace", existing.DestNamespace, requested.DestNamespace))
+	}
+	if requested.DestRegistry != nil && registryID(existing.DestRegistry) != registryID(requested.DestRegistry) {
+		drifted = append(drifted, fieldChange("destinationRegistry", registryID(existing.DestRegistry), registryID(requested.DestRegistry)))
+	}
+	if existing.Enabled != requested.Enabled {
+		drifted = append(drifted, fieldChange("enablePolicy", existing.Enabled, requested.Enabled))
+	}
+	if existing.Override != requested.Override {
+		drifted = append(drifted, fieldChange("override", existing.Override, requested.Override))
+	}
+	if existing.ReplicateDeletion != requested.ReplicateDeletion {
+		drifted = append(drifted, fieldChange("replicateDeletion", existing.ReplicateDeletion, requested.ReplicateDeletion))
+	}
+	if !equalJSON(existing.Filters, requested.Filters) {
+		drifted = append(drifted, "filters")
+	}
+	if requested.Trigger != nil && !equalJSON(existing.Trigger, requested.Trigger) {
+		drifted = append(drifted, "triggerMode")
+	}
+	return drifted
+}
+
+// robotAccountDrift lists the fields of the robot account in Harbor that
+// differ from the requested account.
+func robotAccountDrift(existing *modelv2.Robot, requested *modelv2.RobotCreate) []string {
+	var drifted []string
+	if stripOwner(existing.Description) != stripOwner(requested.Description) {
+		drifted = append(drifted, "description")
+	}
+	if existing.Disable != requested.Disable {
+		drifted = append(drifted, fieldChange("disabled", existing.Disable, requested.Disable))
+	}
+	if existing.Duration != requested.Duration {
+		drifted = append(drifted, fieldChange("duration", existing.Duration, requested.Duration))
+	}
+	if !reflect.DeepEqual(normalizedPermissions(existing.Permissions), normalizedPermissions(requested.Permissions)) {
+		drifted = append(drifted, "permissions")
+	}
+	return drifted
+}
+
+// normalizedPermissions returns the robot account permissions sorted and
+// reduced to the fields the operator sets, so that Harbor returning them in
+// another order or with fields of its own does not count as drift.
+func normalizedPermissions(permissions []*modelv2.RobotPermission) []modelv2.RobotPermission {
+	normalized := make([]modelv2.RobotPermission, 0, len(permissions))
+	for _, permission := range permissions {
+		if permission == nil {
+			continue
+		}
+		access := make([]*modelv2.Access, 0, len(permission.Access))
+		for _, item := range permission.Access {
+			if item == nil {
+				continue
+			}
+			effect := item.Effect
+			if effect == "" {
+				effect = "allow"
+			}
+			access = append(access, &modelv2.Access{Resource: item.Resource, Action: item.Action, Effect: effect})
+		}
+		sort.Slice(access, func(i, j int) bool {
+			a, b := access[i], access[j]
+			if a.Resource != b.Resource {
+				return a.Resource < b.Resource
+			}
+			if a.Action != b.Action {
+				return a.Action < b.Action
+			}
+			return a.Effect < b.Effect
+		})
+		normalized = append(normalized, modelv2.RobotPermission{Kind: permission.Kind, Namespace: permission.Namespace, Access: access})
+	}
+	sort.Slice(normalized, func(i, j int) bool {
+		if normalized[i].Kind != normalized[j].Kind {
+			return normalized[i].Kind < normalized[j].Kind
+		}
+		return normalized[i].Namespace < normalized[j].Namespace
+	})
+	return normalized
+}
+
+func registryID(registry *modelv2.Registry) int64 {
+	if registry == nil {
+		return 0
+	}
+	return registry.ID
+}
+
+// equalJSON compares two Harbor models by their JSON encoding, which treats
+// values Harbor decodes into generic types, such as label filter values, the
+// same as the typed values they were sent as.
+func equalJSON(a, b interface{}) bool {
+	rawA, errA := json.Marshal(a)
+	rawB, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	// nil and empty lists are equivalent for Harbor.
+	if string(rawA) == "null" {
+		rawA = []byte("[]")
+	}
+	if string(rawB) == "null" {
+		rawB = []byte("[]")
+	}
+	return string(rawA) == string(rawB)
+}
+
+// fieldChange describes a changed field with its value in Harbor and in the
+// spec, e.g. `enablePolicy (false -> true)`.
+func fieldChange(field string, existing, requested interface{}) string {
+	return fmt.Sprintf("%s (%v -> %v)", field, existing, requested)
+}
+
+// driftMessage describes the drifted fields of a single Harbor object.
+func driftMessage(kind, name string, fields []string) string {
+	return fmt.Sprintf("%s %q: %s", kind, name, strings.Join(fields, ", "))
+}
+
+// reportDrift sets the Drifted condition from the drift corrected during the
+// reconciliation and emits an event naming the corrected fields.
+func reportDrift(recorder record.EventRecorder, object client.Object, conditions *[]v1.Condition, drift []string) {
+	if len(drift) == 0 {
+		setStatusCondition(conditions, object.GetGeneration(), harborconfigurationv1alpha1.ConditionDrifted, v1.ConditionFalse, harborconfigurationv1alpha1.ReasonNoDrift, "")
+		return
+	}
+
+	driftCorrections.WithLabelValues(resourceKind(object)).Add(float64(len(drift)))
+	message := "corrected drift from spec in Harbor: " + strings.Join(drift, "; ")
+	setStatusCondition(conditions, object.GetGeneration(), harborconfigurationv1alpha1.ConditionDrifted, v1.ConditionTrue, harborconfigurationv1alpha1.ReasonDriftCorrected, message)
+	recorder.Event(object, corev1.EventTypeWarning, harborconfigurationv1alpha1.ReasonDriftCorrected, message)
+}
+
+// resyncAfter schedules the next drift check unless the result already asks
+// for an earlier requeue.
+func resyncAfter(result ctrl.Result, resyncInterval time.Duration) ctrl.Result {
+	if resyncInterval > 0 && (result.RequeueAfter == 0 || resyncInterval < result.RequeueAfter) {
+		result.RequeueAfter = resyncInterval
+	}
+	return result
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	modelv2 "github.com/mittwald/goharbor-client/v5/apiv2/model"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestRobotAccountDriftPermissions(t *testing.T) {
+	requested := []*modelv2.RobotPermission{
+		{Kind: "project", Namespace: "team-a", Access: []*modelv2.Access{
+			{Resource: "repository", Action: "pull", Effect: "allow"},
+			{Resource: "artifact", Action: "read", Effect: "allow"},
+		}},
+		{Kind: "project", Namespace: "shared", Access: []*modelv2.Access{
+			{Resource: "repository", Action: "pull", Effect: "allow"},
+		}},
+	}
+
+	tests := []struct {
+		name     string
+		existing []*modelv2.RobotPermission
+		want     bool
+	}{
+		{
+			name:     "same permissions",
+			existing: requested,
+		},
+		{
+			name: "different order and server set fields",
+			existing: []*modelv2.RobotPermission{
+				{Kind: "project", Namespace: "shared", Access: []*modelv2.Access{
+					{Resource: "repository", Action: "pull"},
+				}},
+				{Kind: "project", Namespace: "team-a", Access: []*modelv2.Access{
+					{Resource: "artifact", Action: "read", Effect: "allow"},
+					{Resource: "repository", Action: "pull", Effect: "allow"},
+				}},
+			},
+		},
+		{
+			name: "missing access",
+			existing: []*modelv2.RobotPermission{
+				{Kind: "project", Namespace: "team-a", Access: []*modelv2.Access{
+					{Resource: "repository", Action: "pull", Effect: "allow"},
+				}},
+				{Kind: "project", Namespace: "shared", Access: []*modelv2.Access{
+					{Resource: "repository", Action: "pull", Effect: "allow"},
+				}},
+			},
+			want: true,
+		},
+		{
+			name: "different effect",
+			existing: []*modelv2.RobotPermission{
+				{Kind: "project", Namespace: "team-a", Access: []*modelv2.Access{
+					{Resource: "repository", Action: "pull", Effect: "deny"},
+					{Resource: "artifact", Action: "read", Effect: "allow"},
+				}},
+				{Kind: "project", Namespace: "shared", Access: []*modelv2.Access{
+					{Resource: "repository", Action: "pull", Effect: "allow"},
+				}},
+			},
+			want: true,
+		},
+		{
+			name: "missing project",
+			existing: []*modelv2.RobotPermission{
+				{Kind: "project", Namespace: "team-a", Access: []*modelv2.Access{
+					{Resource: "repository", Action: "pull", Effect: "allow"},
+					{Resource: "artifact", Action: "read", Effect: "allow"},
+				}},
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existing := &modelv2.Robot{Duration: 30, Permissions: tt.existing}
+			drifted := robotAccountDrift(existing, &modelv2.RobotCreate{Duration: 30, Permissions: requested})
+			if got := len(drifted) > 0; got != tt.want {
+				t.Errorf("robotAccountDrift() = %v, want drift %v", drifted, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplicationPolicyDrift(t *testing.T) {
+	requested := func() *modelv2.ReplicationPolicy {
+		return &modelv2.ReplicationPolicy{
+			Name:          "mirror",
+			Description:   "Mirror [managed-by harbor-config-operator: HarborConfiguration default/mirrors]",
+			SrcRegistry:   &modelv2.Registry{ID: 1},
+			DestNamespace: "team",
+			Enabled:       true,
+			Filters: []*modelv2.ReplicationFilter{
+				{Type: "name", Value: "library/**"},
+				{Type: "label", Value: []string{"release", "stable"}, Decoration: "matches"},
+			},
+			Trigger: &modelv2.ReplicationTrigger{Type: "scheduled", TriggerSettings: &modelv2.ReplicationTriggerSettings{Cron: "0 0 * * * *"}},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(existing *modelv2.ReplicationPolicy)
+		want   []string
+	}{
+		{
+			name:   "unchanged",
+			modify: func(existing *modelv2.ReplicationPolicy) {},
+		},
+		{
+			name: "owner marker and decoded label values",
+			modify: func(existing *modelv2.ReplicationPolicy) {
+				existing.Description = "Mirror"
+				existing.Filters[1].Value = []interface{}{"release", "stable"}
+			},
+		},
+		{
+			name: "registry and flags",
+			modify: func(existing *modelv2.ReplicationPolicy) {
+				existing.SrcRegistry = &modelv2.Registry{ID: 2}
+				existing.Enabled = false
+				existing.Override = true
+			},
+			want: []string{"registry (2 -> 1)", "enablePolicy (false -> true)", "override (true -> false)"},
+		},
+		{
+			name: "description and destination namespace",
+			modify: func(existing *modelv2.ReplicationPolicy) {
+				existing.Description = "Changed in the UI"
+				existing.DestNamespace = "other"
+			},
+			want: []string{"description", "destinationNamespace (other -> team)"},
+		},
+		{
+			name: "filters and trigger",
+			modify: func(existing *modelv2.ReplicationPolicy) {
+				existing.Filters = existing.Filters[:1]
+				existing.Trigger = &modelv2.ReplicationTrigger{Type: "manual"}
+			},
+			want: []string{"filters", "triggerMode"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existing := requested()
+			tt.modify(existing)
+			if got := replicationPolicyDrift(existing, requested()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("replicationPolicyDrift() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResyncAfter(t *testing.T) {
+	tests := []struct {
+		name           string
+		result         ctrl.Result
+		resyncInterval time.Duration
+		want           ctrl.Result
+	}{
+		{
+			name:           "resync",
+			resyncInterval: 10 * time.Minute,
+			want:           ctrl.Result{RequeueAfter: 10 * time.Minute},
+		},
+		{
+			name:   "resync disabled",
+			result: ctrl.Result{RequeueAfter: time.Minute},
+			want:   ctrl.Result{RequeueAfter: time.Minute},
+		},
+		{
+			name:           "earlier requeue kept",
+			result:         ctrl.Result{RequeueAfter: time.Minute},
+			resyncInterval: 10 * time.Minute,
+			want:           ctrl.Result{RequeueAfter: time.Minute},
+		},
+		{
+			name:           "later requeue replaced",
+			result:         ctrl.Result{RequeueAfter: time.Hour},
+			resyncInterval: 10 * time.Minute,
+			want:           ctrl.Result{RequeueAfter: 10 * time.Minute},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resyncAfter(tt.result, tt.resyncInterval); got != tt.want {
+				t.Errorf("resyncAfter() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+// harborEvents records events on a resource about the Harbor objects it
+// manages. The zero value records nothing.
+type harborEvents struct {
+	recorder record.EventRecorder
+	object   client.Object
+}
+
+func (e harborEvents) eventf(eventType, reason, messageFmt string, args ...interface{}) {
+	if e.recorder == nil || e.object == nil {
+		return
+	}
+	e.recorder.Eventf(e.object, eventType, reason, messageFmt, args...)
+}
+
+// reconciled records the creation or update of a Harbor object from the
+// fields reported by its reconciliation.
+func (e harborEvents) reconciled(kind, name string, id int64, changed []string) {
+	switch {
+	case len(changed) == 1 && changed[0] == driftDeleted:
+		e.eventf(corev1.EventTypeNormal, harborconfigurationv1alpha1.ReasonCreated, "Created %s %q (ID %d)", kind, name, id)
+	case len(changed) > 0:
+		e.eventf(corev1.EventTypeNormal, harborconfigurationv1alpha1.ReasonUpdated, "Updated %s %q (ID %d): %s", kind, name, id, strings.Join(changed, ", "))
+	}
+}
+
+func (e harborEvents) adopted(kind, name string, id int64, previousOwner string) {
+	if previousOwner == "" {
+		previousOwner = "no resource"
+	}
+	e.eventf(corev1.EventTypeNormal, harborconfigurationv1alpha1.ReasonAdopted, "Adopted %s %q (ID %d) from %s", kind, name, id, previousOwner)
+}
+
+func (e harborEvents) deleted(kind, name string, id int64) {
+	e.eventf(corev1.EventTypeNormal, harborconfigurationv1alpha1.ReasonDeleted, "Deleted %s %q (ID %d)", kind, name, id)
+}
+
+func (e harborEvents) deleteFailed(kind, name string, id int64, err error) {
+	e.eventf(corev1.EventTypeWarning, harborconfigurationv1alpha1.ReasonDeleteFailed, "Failed to delete %s %q (ID %d): %v", kind, name, id, err)
+}
+
+func (e harborEvents) orphaned(kind, name string, id int64) {
+	e.eventf(corev1.EventTypeNormal, harborconfigurationv1alpha1.ReasonOrphaned, "Orphaned %s %q (ID %d)", kind, name, id)
+}
+
+func (e harborEvents) releaseFailed(kind, name string, err error) {
+	e.eventf(corev1.EventTypeWarning, harborconfigurationv1alpha1.ReasonReleaseFailed, "Failed to remove the ownership marker of orphaned %s %q: %v", kind, name, err)
+}
+
+// deleteAbandoned records that the Harbor objects of a deleted resource were
+// left in Harbor as its Harbor target no longer exists.
+func (e harborEvents) deleteAbandoned(err error) {
+	e.eventf(corev1.EventTypeWarning, harborconfigurationv1alpha1.ReasonDeleteAbandoned, "Left the Harbor objects in Harbor, the Harbor target no longer exists: %v", err)
+}
+
+// orphanedUnreleased records that the Harbor objects of a deleted resource
+// were orphaned without removing their ownership marker, as Harbor could not
+// be reached.
+func (e harborEvents) orphanedUnreleased(err error) {
+	e.eventf(corev1.EventTypeWarning, harborconfigurationv1alpha1.ReasonHarborUnavailable, "Orphaned the Harbor objects without removing their ownership marker: %v", err)
+}
+
+// harborUnavailable records that Harbor cannot be reached, unless conditions
+// already report the same error, so that retries do not repeat the warning.
+func (e harborEvents) harborUnavailable(conditions []v1.Condition, err error) {
+	ready := meta.FindStatusCondition(conditions, harborconfigurationv1alpha1.ConditionReady)
+	if ready != nil && ready.Status == v1.ConditionFalse && ready.Reason == harborconfigurationv1alpha1.ReasonHarborUnavailable && ready.Message == err.Error() {
+		return
+	}
+	e.eventf(corev1.EventTypeWarning, harborconfigurationv1alpha1.ReasonHarborUnavailable, "%v", err)
+}
+
+func (e harborEvents) replicationTriggered(name string, id, executionID int64) {
+	e.eventf(corev1.EventTypeNormal, harborconfigurationv1alpha1.ReasonReplicationTriggered, "Triggered replication rule %q (ID %d), execution %d", name, id, executionID)
+}
+
+func (e harborEvents) replicationTriggerFailed(name string, id int64, err error) {
+	e.eventf(corev1.EventTypeWarning, harborconfigurationv1alpha1.ReasonReplicationTriggerFailed, "Failed to trigger replication rule %q (ID %d): %v", name, id, err)
+}
+
+func (e harborEvents) replicationDisabled(name string, id int64) {
+	e.eventf(corev1.EventTypeWarning, harborconfigurationv1alpha1.ReasonReplicationDisabled, "Not running replication rule %q (ID %d) for runRequest, the rule is disabled", name, id)
+}
+
+func (e harborEvents) credentialsRefreshed(name string, id int64) {
+	e.eventf(corev1.EventTypeNormal, harborconfigurationv1alpha1.ReasonCredentialsRefreshed, "Refreshed the credentials of robot account %q (ID %d)", name, id)
+}
+
+// failed records a failed reconciliation of a Harbor object.
+func (e harborEvents) failed(kind, name string, err error) {
+	reason := harborconfigurationv1alpha1.ReasonReconcileFailed
+	var conflict *ownershipConflictError
+	if errors.As(err, &conflict) {
+		reason = harborconfigurationv1alpha1.ReasonOwnershipConflict
+	}
+	e.eventf(corev1.EventTypeWarning, reason, "Failed to reconcile %s %q: %v", kind, name, err)
+}
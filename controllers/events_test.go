@@ -0,0 +1,177 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	modelv2 "github.com/mittwald/goharbor-client/v5/apiv2/model"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+func TestHarborEvents(t *testing.T) {
+	unavailable := errors.New("connection refused")
+	tests := []struct {
+		name   string
+		record func(events harborEvents)
+		want   string
+	}{
+		{
+			name: "claim conflict",
+			record: func(events harborEvents) {
+				events.failed("registry", "docker-hub", fmt.Errorf("claiming: %w", &ownershipConflictError{kind: "registry", name: "docker-hub", owner: "HarborConfiguration team-b/mirrors"}))
+			},
+			want: `Warning OwnershipConflict Failed to reconcile registry "docker-hub": claiming: registry "docker-hub" already exists in Harbor and is managed by HarborConfiguration team-b/mirrors`,
+		},
+		{
+			name: "claim conflict with an object not managed by the operator",
+			record: func(events harborEvents) {
+				events.failed("project", "library", &ownershipConflictError{kind: "project", name: "library"})
+			},
+			want: `Warning OwnershipConflict Failed to reconcile project "library": project "library" already exists in Harbor and is not managed by the operator`,
+		},
+		{
+			name: "reconcile failure",
+			record: func(events harborEvents) {
+				events.failed("project", "library", unavailable)
+			},
+			want: `Warning ReconcileFailed Failed to reconcile project "library": connection refused`,
+		},
+		{
+			name: "harbor unavailable",
+			record: func(events harborEvents) {
+				events.harborUnavailable(nil, unavailable)
+			},
+			want: "Warning HarborUnavailable connection refused",
+		},
+		{
+			name: "deletion orphaned",
+			record: func(events harborEvents) {
+				events.orphaned("replication rule", "pull", 4)
+			},
+			want: `Normal Orphaned Orphaned replication rule "pull" (ID 4)`,
+		},
+		{
+			name: "deletion orphaned with harbor unavailable",
+			record: func(events harborEvents) {
+				events.orphanedUnreleased(unavailable)
+			},
+			want: "Warning HarborUnavailable Orphaned the Harbor objects without removing their ownership marker: connection refused",
+		},
+		{
+			name: "created",
+			record: func(events harborEvents) {
+				events.reconciled("registry", "docker-hub", 1, []string{driftDeleted})
+			},
+			want: `Normal Created Created registry "docker-hub" (ID 1)`,
+		},
+		{
+			name: "updated",
+			record: func(events harborEvents) {
+				events.reconciled("registry", "docker-hub", 1, []string{"url", "credential"})
+			},
+			want: `Normal Updated Updated registry "docker-hub" (ID 1): url, credential`,
+		},
+		{
+			name: "unchanged",
+			record: func(events harborEvents) {
+				events.reconciled("registry", "docker-hub", 1, nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(1)
+			tt.record(harborEvents{recorder: recorder, object: &harborconfigurationv1alpha1.HarborConfiguration{}})
+
+			var got string
+			select {
+			case got = <-recorder.Events:
+			default:
+			}
+			if got != tt.want {
+				t.Errorf("event = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// The zero value records nothing.
+	harborEvents{}.harborUnavailable(nil, unavailable)
+}
+
+func TestHarborConfigurationUnavailableEvents(t *testing.T) {
+	harborConfiguration := &harborconfigurationv1alpha1.HarborConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "mirrors", Generation: 1},
+		Spec: harborconfigurationv1alpha1.HarborConfigurationSpec{
+			Registries: []harborconfigurationv1alpha1.Registry{{Name: "docker-hub", Provider: "docker-hub"}},
+		},
+	}
+	harbor := &fakeConfigurationHarbor{registries: map[string]*modelv2.Registry{}}
+	recorder := record.NewFakeRecorder(10)
+	r, c := newConfigurationReconciler(t, harborConfiguration, harbor, recorder)
+	ctx := context.Background()
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "harbor-admin"}}
+	if err := c.Delete(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+	reconcile := func() {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(harborConfiguration)}); err == nil {
+			t.Fatal("Reconcile() succeeded without the credentials Secret")
+		}
+	}
+
+	// Retries with the same error do not repeat the warning.
+	reconcile()
+	reconcile()
+	reconcile()
+
+	// A different error is reported again.
+	secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "harbor-admin"},
+		Data:       map[string][]byte{"username": []byte("admin")},
+	}
+	if err := c.Create(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+	reconcile()
+	reconcile()
+
+	close(recorder.Events)
+	var events []string
+	for event := range recorder.Events {
+		events = append(events, event)
+	}
+	want := []string{
+		`Warning HarborUnavailable secrets "harbor-admin" not found`,
+		`Warning HarborUnavailable no key "password" found in Secret team-a/harbor-admin`,
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %q, want %q", events, want)
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-openapi/runtime"
+	"github.com/go-openapi/strfmt"
+	modelv2 "github.com/mittwald/goharbor-client/v5/apiv2/model"
+)
+
+// getUserGroup returns the user group with the given ID.
+func (c *harborClient) getUserGroup(ctx context.Context, id int64) (*modelv2.UserGroup, error) {
+	var userGroup modelv2.UserGroup
+	err := c.submit(ctx, "getUserGroup", http.MethodGet, "/usergroups/{group_id}", map[string]string{
+		"group_id": strconv.FormatInt(id, 10),
+	}, nil, nil, &userGroup)
+	if err != nil {
+		return nil, err
+	}
+	return &userGroup, nil
+}
+
+// setProjectMemberRole changes the role of the project member with the given
+// ID. goharbor-client looks members up by name, which does not tell groups of
+// different types apart.
+func (c *harborClient) setProjectMemberRole(ctx context.Context, projectName string, memberID, roleID int64) error {
+	return c.submit(ctx, "updateProjectMember", http.MethodPut, "/projects/{project_name_or_id}/members/{mid}", map[string]string{
+		"project_name_or_id": projectName,
+		"mid":                strconv.FormatInt(memberID, 10),
+	}, nil, &modelv2.RoleRequest{RoleID: roleID}, nil)
+}
+
+// removeProjectMember removes the project member with the given ID.
+func (c *harborClient) removeProjectMember(ctx context.Context, projectName string, memberID int64) error {
+	return c.submit(ctx, "deleteProjectMember", http.MethodDelete, "/projects/{project_name_or_id}/members/{mid}", map[string]string{
+		"project_name_or_id": projectName,
+		"mid":                strconv.FormatInt(memberID, 10),
+	}, nil, nil, nil)
+}
+
+// latestReplicationExecution returns the execution of the replication rule
+// with the given trigger and, unless empty, status that started last, or nil
+// when there is none. Unlike goharbor-client, which pages through every
+// execution of the rule, it only fetches the first one.
+func (c *harborClient) latestReplicationExecution(ctx context.Context, policyID int64, status, trigger string) (*modelv2.ReplicationExecution, error) {
+	query := map[string]string{
+		"policy_id": strconv.FormatInt(policyID, 10),
+		"trigger":   trigger,
+		"sort":      "-start_time",
+		"page":      "1",
+		"page_size": "1",
+	}
+	if status != "" {
+		query["status"] = status
+	}
+	var executions []*modelv2.ReplicationExecution
+	if err := c.submit(ctx, "listReplicationExecutions", http.MethodGet, "/replication/executions", nil, query, nil, &executions); err != nil {
+		return nil, err
+	}
+	if len(executions) == 0 {
+		return nil, nil
+	}
+	return executions[0], nil
+}
+
+// submit sends a request goharbor-client offers no call for, decoding the
+// response into result unless it is nil. Projects are always named, never
+// referred to by ID.
+func (c *harborClient) submit(ctx context.Context, id, method, pathPattern string, pathParams, queryParams map[string]string, body, result interface{}) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	_, err := c.transport.Submit(&runtime.ClientOperation{
+		ID:                 id,
+		Method:             method,
+		PathPattern:        pathPattern,
+		ProducesMediaTypes: []string{runtime.JSONMime},
+		ConsumesMediaTypes: []string{runtime.JSONMime},
+		AuthInfo:           c.authInfo,
+		Params: runtime.ClientRequestWriterFunc(func(req runtime.ClientRequest, _ strfmt.Registry) error {
+			for name, value := range pathParams {
+				if err := req.SetPathParam(name, value); err != nil {
+					return err
+				}
+			}
+			for name, value := range queryParams {
+				if err := req.SetQueryParam(name, value); err != nil {
+					return err
+				}
+			}
+			if _, ok := pathParams["project_name_or_id"]; ok {
+				if err := req.SetHeaderParam("X-Is-Resource-Name", "true"); err != nil {
+					return err
+				}
+			}
+			if body != nil {
+				return req.SetBodyParam(body)
+			}
+			return nil
+		}),
+		Reader: runtime.ClientResponseReaderFunc(func(resp runtime.ClientResponse, consumer runtime.Consumer) (interface{}, error) {
+			if resp.Code() < 200 || resp.Code() >= 300 {
+				return nil, runtime.NewAPIError(id, resp.Message(), resp.Code())
+			}
+			if result == nil {
+				return nil, nil
+			}
+			return result, consumer.Consume(resp.Body(), result)
+		}),
+		Context: ctx,
+	})
+	return err
+}
@@ -0,0 +1,319 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-openapi/runtime"
+	runtimeclient "github.com/go-openapi/runtime/client"
+	"github.com/go-openapi/strfmt"
+	harborOperator "github.com/goharbor/harbor-operator/apis/goharbor.io/v1beta1"
+	apiv2 "github.com/mittwald/goharbor-client/v5/apiv2"
+	"github.com/mittwald/goharbor-client/v5/apiv2/pkg/config"
+	harborerrors "github.com/mittwald/goharbor-client/v5/apiv2/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+var (
+	harborClusterResource = schema.GroupResource{
+		Group:    "goharbor.io",
+		Resource: "harborclusters",
+	}
+
+	// harborClusterVersion is the HarborCluster version served by the API
+	// server that is used to read HarborClusters.
+	harborClusterVersion preferredVersion
+)
+
+// harborClientIdleTimeout is how long a pooled Harbor API client is kept
+// without being used, e.g. after the resources of its target were deleted.
+const harborClientIdleTimeout = 30 * time.Minute
+
+// harborEndpoint is what is needed to talk to a Harbor instance.
+type harborEndpoint struct {
+	url      string
+	username string
+	password string
+	tls      harborTLS
+	timeout  time.Duration
+	// registry is the host images are pulled from, which differs from the
+	// host of url when Harbor core is reached through its in-cluster service.
+	registry string
+}
+
+// HarborClientPool hands out Harbor API clients. Targets are resolved from the
+// informer cache, and the client of a target is reused until the resolved
+// endpoint, credentials or TLS settings change, e.g. because the HarborCluster,
+// the HarborInstance or the credentials Secret was updated. Clients that were
+// not used for harborClientIdleTimeout are dropped.
+type HarborClientPool struct {
+	reader    client.Reader
+	discovery discovery.DiscoveryInterface
+
+	mu      sync.Mutex
+	clients map[string]pooledHarborClient
+}
+
+type pooledHarborClient struct {
+	// version identifies the endpoint, credentials and TLS settings the client
+	// was built with.
+	version  string
+	client   *harborClient
+	lastUsed time.Time
+}
+
+// harborClient is a Harbor API client. It extends the goharbor-client one
+// with the calls goharbor-client does not offer, which are sent through the
+// same transport.
+type harborClient struct {
+	*apiv2.RESTClient
+	transport  *runtimeclient.Runtime
+	authInfo   runtime.ClientAuthInfoWriter
+	timeout    time.Duration
+	httpClient *http.Client
+	// id identifies the client in harborTransports.
+	id string
+}
+
+// NewHarborClientPool returns a pool reading HarborClusters, HarborInstances,
+// Secrets and ConfigMaps from reader, which should be backed by a cache, such
+// as the one of NewHarborTargetReader.
+func NewHarborClientPool(reader client.Reader, discoveryClient discovery.DiscoveryInterface) *HarborClientPool {
+	return &HarborClientPool{
+		reader:    reader,
+		discovery: discoveryClient,
+		clients:   map[string]pooledHarborClient{},
+	}
+}
+
+// Get returns a Harbor API client for the Harbor instance the target of a
+// resource in namespace points at.
+func (p *HarborClientPool) Get(ctx context.Context, namespace string, target harborconfigurationv1alpha1.HarborTarget) (*harborClient, error) {
+	endpoint, err := resolveHarborTarget(ctx, p.reader, p.discovery, namespace, target)
+	if err != nil {
+		return nil, err
+	}
+	if target.TLS != nil {
+		if err := getTargetTLS(ctx, p.reader, namespace, *target.TLS, &endpoint.tls); err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := json.Marshal(struct {
+		Namespace string                                   `json:"namespace"`
+		Target    harborconfigurationv1alpha1.HarborTarget `json:"target"`
+	}{namespace, target})
+	if err != nil {
+		return nil, err
+	}
+	version := endpoint.version()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	p.evictIdle(now)
+
+	pooled, ok := p.clients[string(key)]
+	if ok && pooled.version == version {
+		pooled.lastUsed = now
+		p.clients[string(key)] = pooled
+		return pooled.client, nil
+	}
+	if ok {
+		pooled.client.close()
+	}
+
+	harborClient, err := newHarborClient(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	p.clients[string(key)] = pooledHarborClient{version: version, client: harborClient, lastUsed: now}
+	return harborClient, nil
+}
+
+// evictIdle drops the clients that were not used for harborClientIdleTimeout.
+// p.mu must be held.
+func (p *HarborClientPool) evictIdle(now time.Time) {
+	for key, pooled := range p.clients {
+		if now.Sub(pooled.lastUsed) > harborClientIdleTimeout {
+			pooled.client.close()
+			delete(p.clients, key)
+		}
+	}
+}
+
+// Registry returns the host images are pulled from for the Harbor instance
+// the target of a resource in namespace points at.
+func (p *HarborClientPool) Registry(ctx context.Context, namespace string, target harborconfigurationv1alpha1.HarborTarget) (string, error) {
+	endpoint, err := resolveHarborTarget(ctx, p.reader, p.discovery, namespace, target)
+	if err != nil {
+		return "", err
+	}
+	if endpoint.registry == "" {
+		return "", errors.New("the registry host of the Harbor target is unknown")
+	}
+	return endpoint.registry, nil
+}
+
+// version returns a digest of the endpoint, so that credentials are not kept
+// in plain text by the pool.
+func (e harborEndpoint) version() string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%#v", e)))
+	return hex.EncodeToString(sum[:])
+}
+
+// newHarborClient builds a Harbor API client for an endpoint.
+func newHarborClient(endpoint harborEndpoint) (*harborClient, error) {
+	harborURL, err := url.Parse(strings.TrimSuffix(endpoint.url, "/v2.0") + "/v2.0")
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := newHarborHTTPClient(endpoint.tls)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS settings for %s: %w", harborURL.Host, err)
+	}
+
+	opts := config.Defaults()
+	if endpoint.timeout > 0 {
+		opts = opts.WithTimeout(endpoint.timeout)
+	}
+	authInfo := runtimeclient.BasicAuth(endpoint.username, endpoint.password)
+	id := harborTransports.add(httpClient.Transport)
+	restClient, err := apiv2.NewRESTClientWithAuthFunc(harborURL.String(), func(req runtime.ClientRequest, formats strfmt.Registry) error {
+		if err := req.SetHeaderParam(harborEndpointHeader, id); err != nil {
+			return err
+		}
+		return authInfo.AuthenticateRequest(req, formats)
+	}, opts)
+	if err != nil {
+		harborTransports.remove(id)
+		return nil, err
+	}
+	return &harborClient{
+		RESTClient: restClient,
+		transport:  runtimeclient.NewWithClient(harborURL.Host, harborURL.Path, []string{harborURL.Scheme}, httpClient),
+		authInfo:   authInfo,
+		timeout:    opts.Timeout,
+		httpClient: httpClient,
+		id:         id,
+	}, nil
+}
+
+// close releases the transport of the client.
+func (c *harborClient) close() {
+	harborTransports.remove(c.id)
+	c.httpClient.CloseIdleConnections()
+}
+
+// harborAPIURL adds the /api/v2.0 path to a Harbor URL when it is missing.
+func harborAPIURL(u string) string {
+	u = strings.TrimSuffix(strings.TrimSuffix(u, "/"), "/v2.0")
+	if !strings.HasSuffix(u, "/api") {
+		u += "/api"
+	}
+	return u + "/v2.0"
+}
+
+// registryHost returns the host of a Harbor URL.
+func registryHost(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	return parsed.Host
+}
+
+// HarborRegistryExists returns a lookup reporting whether a registry exists in
+// the Harbor instance a HarborTarget points at.
+func HarborRegistryExists(harborClients *HarborClientPool) harborconfigurationv1alpha1.RegistryLookup {
+	return func(ctx context.Context, namespace string, target harborconfigurationv1alpha1.HarborTarget, name string) (bool, error) {
+		client, err := harborClients.Get(ctx, namespace, target)
+		if err != nil {
+			return false, err
+		}
+
+		_, err = client.GetRegistryByName(ctx, name)
+		if errors.Is(err, &harborerrors.ErrRegistryNotFound{}) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+}
+
+func getHarborSecret(ctx context.Context, reader client.Reader, harborcluster *harborOperator.HarborCluster) (string, error) {
+	var passwordSecret corev1.Secret
+	err := reader.Get(ctx, types.NamespacedName{Namespace: harborcluster.Namespace, Name: harborcluster.Spec.HarborAdminPasswordRef}, &passwordSecret)
+	if err != nil {
+		return "", err
+	}
+	passwords := passwordSecret.Data
+	for key, value := range passwords {
+		if key == "secret" && string(value) != "" {
+			return string(value), nil
+		}
+	}
+	return "", errors.New("no key \"secret\" found")
+}
+
+func getHarborURL(harborcluster *harborOperator.HarborCluster) string {
+	url := os.Getenv("HARBOR_CORE_URL")
+	if url == "" {
+		url = fmt.Sprintf("%s://%s-harbor-harbor-core.%s/api/v2.0", harborcluster.Spec.InternalTLS.GetScheme(), harborcluster.Name, harborcluster.Namespace)
+	}
+	return url
+}
+
+// getConcreteHarborType reads a HarborCluster in any served version into the
+// v1beta1 type, which agrees with v1alpha3 on the fields used here.
+func getConcreteHarborType(ctx context.Context, reader client.Reader, gvr schema.GroupVersionResource, key types.NamespacedName, harborTarget harborOperator.HarborCluster) (harborOperator.HarborCluster, error) {
+	harborUnstructured := &unstructured.Unstructured{}
+	harborUnstructured.SetGroupVersionKind(gvr.GroupVersion().WithKind("HarborCluster"))
+	err := reader.Get(ctx, key, harborUnstructured)
+	if err != nil {
+		return harborTarget, err
+	}
+
+	err = k8sruntime.DefaultUnstructuredConverter.FromUnstructured(harborUnstructured.UnstructuredContent(), &harborTarget)
+	if err != nil {
+		return harborTarget, err
+	}
+	return harborTarget, err
+}
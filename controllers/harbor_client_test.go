@@ -0,0 +1,65 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+func TestHarborClientPool(t *testing.T) {
+	reader := fake.NewClientBuilder().WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team", Name: "harbor-admin"},
+		Data:       map[string][]byte{"username": []byte("admin"), "password": []byte("secret")},
+	}).Build()
+	pool := NewHarborClientPool(reader, nil)
+	target := harborconfigurationv1alpha1.HarborTarget{
+		URL:                  "https://harbor.example.com",
+		CredentialsSecretRef: &harborconfigurationv1alpha1.LocalHarborCredentialsSecretReference{Name: "harbor-admin"},
+	}
+	ctx := context.Background()
+
+	first, err := pool.Get(ctx, "team", target)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if again, _ := pool.Get(ctx, "team", target); again != first {
+		t.Error("Get() built a new client for an unchanged target")
+	}
+
+	insecure := target
+	insecure.TLS = &harborconfigurationv1alpha1.HarborTLS{InsecureSkipVerify: true}
+	if other, _ := pool.Get(ctx, "team", insecure); other == first {
+		t.Error("Get() reused the client of a target with other TLS settings")
+	}
+
+	if _, err := pool.Get(ctx, "other-team", target); err == nil {
+		t.Error("Get() read the credentials Secret of another namespace")
+	}
+
+	pool.evictIdle(time.Now().Add(harborClientIdleTimeout + time.Minute))
+	if len(pool.clients) != 0 {
+		t.Errorf("evictIdle() kept %d idle clients", len(pool.clients))
+	}
+}
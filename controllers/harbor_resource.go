@@ -0,0 +1,149 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	controllerutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+// harborResource gives the reconcilers of the resources managing a single
+// Harbor object, such as HarborProject and HarborRobotAccount, access to the
+// status fields they share.
+type harborResource struct {
+	object             client.Object
+	conditions         *[]v1.Condition
+	observedGeneration *int64
+	// kind labels the Harbor object in the managed objects metric.
+	kind string
+	// managed reports whether the status records the Harbor object.
+	managed func() bool
+}
+
+// setReady sets the Ready condition of the resource.
+func (h harborResource) setReady(status v1.ConditionStatus, reason, message string) {
+	setStatusCondition(h.conditions, h.object.GetGeneration(), harborconfigurationv1alpha1.ConditionReady, status, reason, message)
+}
+
+// reconciled reports whether the current spec had been reconciled before, in
+// which case differences found in Harbor are drift.
+func (h harborResource) reconciled() bool {
+	return *h.observedGeneration == h.object.GetGeneration() && meta.IsStatusConditionTrue(*h.conditions, harborconfigurationv1alpha1.ConditionReady)
+}
+
+// updateStatus writes the status of the resource and records it in the
+// metrics.
+func (h harborResource) updateStatus(ctx context.Context, c client.StatusClient) error {
+	*h.observedGeneration = h.object.GetGeneration()
+	managed := 0
+	if h.managed() {
+		managed = 1
+	}
+	recordResourceStatus(h.object, *h.conditions, map[string]int{h.kind: managed})
+	return c.Status().Update(ctx, h.object)
+}
+
+// harborUnavailable reports that the Harbor instance the resource targets
+// cannot be reached and returns err so that the reconciliation is retried.
+func (h harborResource) harborUnavailable(ctx context.Context, c client.StatusClient, err error) (ctrl.Result, error) {
+	h.setReady(v1.ConditionFalse, harborconfigurationv1alpha1.ReasonHarborUnavailable, err.Error())
+	if statusErr := h.updateStatus(ctx, c); statusErr != nil {
+		return ctrl.Result{}, statusErr
+	}
+	return ctrl.Result{}, err
+}
+
+// finish writes the status at the end of a reconciliation that ended with
+// err and schedules the next drift check. A failure to write the status is
+// only returned when the reconciliation itself succeeded.
+func (h harborResource) finish(ctx context.Context, c client.StatusClient, result ctrl.Result, resyncInterval time.Duration, err error) (ctrl.Result, error) {
+	if statusErr := h.updateStatus(ctx, c); statusErr != nil && err == nil {
+		return ctrl.Result{}, statusErr
+	}
+	return resyncAfter(result, resyncInterval), err
+}
+
+// addFinalizer adds the finalizer to the resource unless it already has it.
+func addFinalizer(ctx context.Context, c client.Writer, object client.Object) error {
+	if controllerutil.ContainsFinalizer(object, harborFinaliserName) {
+		return nil
+	}
+	controllerutil.AddFinalizer(object, harborFinaliserName)
+	return c.Update(ctx, object)
+}
+
+// removeFinalizer releases a resource whose Harbor objects have been cleaned
+// up and drops its metrics.
+func removeFinalizer(ctx context.Context, c client.Writer, object client.Object) error {
+	controllerutil.RemoveFinalizer(object, harborFinaliserName)
+	forgetResource(object)
+	return c.Update(ctx, object)
+}
+
+// finalizeWithoutHarbor finishes the deletion of a resource whose Harbor
+// target could not be resolved, e.g. because its HarborCluster or credentials
+// Secret went away with the namespace. Orphaned Harbor objects need no Harbor
+// call, and the ones to be deleted are left behind once the target is known
+// to be gone, so that the deletion of the resource is not held up forever.
+func finalizeWithoutHarbor(ctx context.Context, c client.Writer, events harborEvents, object client.Object, orphan bool, err error) (ctrl.Result, error) {
+	switch {
+	case orphan:
+		events.orphanedUnreleased(err)
+	case harborTargetGone(err):
+		events.deleteAbandoned(err)
+	default:
+		events.harborUnavailable(nil, err)
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, removeFinalizer(ctx, c, object)
+}
+
+// harborTargetGone reports whether the Harbor target could not be resolved
+// because it, or the kind of resource it names, does not exist.
+func harborTargetGone(err error) bool {
+	return apierrors.IsNotFound(err) || meta.IsNoMatchError(err)
+}
+
+// listRequests lists the resources matching opts and returns a reconcile
+// request for each of them, or none when they cannot be listed.
+func listRequests(c client.Reader, list client.ObjectList, opts ...client.ListOption) []reconcile.Request {
+	if err := c.List(context.Background(), list, opts...); err != nil {
+		return nil
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(items))
+	for _, item := range items {
+		if object, ok := item.(client.Object); ok {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(object)})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+func TestListRequests(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := harborconfigurationv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	project := func(namespace, name string) *harborconfigurationv1alpha1.HarborProject {
+		return &harborconfigurationv1alpha1.HarborProject{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	}
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		project("team-a", "images"),
+		project("team-a", "charts"),
+		project("team-b", "images"),
+	).Build()
+
+	tests := []struct {
+		name string
+		opts []client.ListOption
+		want []reconcile.Request
+	}{
+		{
+			name: "namespace",
+			opts: []client.ListOption{client.InNamespace("team-a")},
+			want: []reconcile.Request{
+				{NamespacedName: types.NamespacedName{Namespace: "team-a", Name: "charts"}},
+				{NamespacedName: types.NamespacedName{Namespace: "team-a", Name: "images"}},
+			},
+		},
+		{
+			name: "no match",
+			opts: []client.ListOption{client.InNamespace("team-c")},
+			want: []reconcile.Request{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := listRequests(reader, &harborconfigurationv1alpha1.HarborProjectList{}, tt.opts...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("listRequests() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFinalizer(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := harborconfigurationv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	project := &harborconfigurationv1alpha1.HarborProject{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "images"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(project).Build()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := addFinalizer(ctx, c, project); err != nil {
+			t.Fatalf("addFinalizer() error = %v", err)
+		}
+	}
+	var stored harborconfigurationv1alpha1.HarborProject
+	if err := c.Get(ctx, client.ObjectKeyFromObject(project), &stored); err != nil {
+		t.Fatal(err)
+	}
+	if got := stored.GetFinalizers(); !reflect.DeepEqual(got, []string{harborFinaliserName}) {
+		t.Errorf("finalizers after addFinalizer() = %v, want [%s]", got, harborFinaliserName)
+	}
+
+	if err := removeFinalizer(ctx, c, &stored); err != nil {
+		t.Fatalf("removeFinalizer() error = %v", err)
+	}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(project), &stored); err != nil {
+		t.Fatal(err)
+	}
+	if controllerutil.ContainsFinalizer(&stored, harborFinaliserName) {
+		t.Errorf("finalizers after removeFinalizer() = %v, want none", stored.GetFinalizers())
+	}
+}
+
+func TestFinalizeWithoutHarbor(t *testing.T) {
+	notFound := apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "harbor-admin")
+	unavailable := errors.New("connection refused")
+
+	tests := []struct {
+		name          string
+		orphan        bool
+		err           error
+		wantErr       bool
+		wantFinalizer bool
+		wantEvent     string
+	}{
+		{
+			name:      "orphan",
+			orphan:    true,
+			err:       unavailable,
+			wantEvent: "Warning HarborUnavailable Orphaned the Harbor objects without removing their ownership marker: connection refused",
+		},
+		{
+			name:      "delete with the target gone",
+			err:       notFound,
+			wantEvent: `Warning DeleteAbandoned Left the Harbor objects in Harbor, the Harbor target no longer exists: secrets "harbor-admin" not found`,
+		},
+		{
+			name:          "delete with the target unavailable",
+			err:           unavailable,
+			wantErr:       true,
+			wantFinalizer: true,
+			wantEvent:     "Warning HarborUnavailable connection refused",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := &harborconfigurationv1alpha1.HarborProject{ObjectMeta: metav1.ObjectMeta{
+				Namespace:  "team-a",
+				Name:       "images",
+				Finalizers: []string{harborFinaliserName},
+			}}
+			c := fake.NewClientBuilder().WithScheme(newTargetScheme(t)).WithObjects(project).Build()
+			recorder := record.NewFakeRecorder(1)
+
+			_, err := finalizeWithoutHarbor(context.Background(), c, harborEvents{recorder: recorder, object: project}, project, tt.orphan, tt.err)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("finalizeWithoutHarbor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var stored harborconfigurationv1alpha1.HarborProject
+			if err := c.Get(context.Background(), client.ObjectKeyFromObject(project), &stored); err != nil {
+				t.Fatal(err)
+			}
+			if got := controllerutil.ContainsFinalizer(&stored, harborFinaliserName); got != tt.wantFinalizer {
+				t.Errorf("finalizer kept = %v, want %v", got, tt.wantFinalizer)
+			}
+			if got := <-recorder.Events; got != tt.wantEvent {
+				t.Errorf("event = %q, want %q", got, tt.wantEvent)
+			}
+		})
+	}
+}
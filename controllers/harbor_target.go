@@ -0,0 +1,236 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	harborOperator "github.com/goharbor/harbor-operator/apis/goharbor.io/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+// harborTargetResolver resolves a HarborTarget of a resource in namespace into
+// the endpoint of a Harbor instance. It reports false when it does not handle
+// the target.
+type harborTargetResolver func(ctx context.Context, reader client.Reader, discoveryClient discovery.DiscoveryInterface, namespace string, target harborconfigurationv1alpha1.HarborTarget) (harborEndpoint, bool, error)
+
+// harborTargetResolvers are tried in order until one handles the target.
+var harborTargetResolvers = []harborTargetResolver{
+	resolveHarborURL,
+	resolveHarborInstance,
+	resolveHarborCluster,
+}
+
+func resolveHarborTarget(ctx context.Context, reader client.Reader, discoveryClient discovery.DiscoveryInterface, namespace string, target harborconfigurationv1alpha1.HarborTarget) (harborEndpoint, error) {
+	for _, resolve := range harborTargetResolvers {
+		endpoint, ok, err := resolve(ctx, reader, discoveryClient, namespace, target)
+		if ok || err != nil {
+			return endpoint, err
+		}
+	}
+	return harborEndpoint{}, errors.New("harborTarget must set url, instanceRef or name")
+}
+
+// resolveHarborURL handles targets naming the URL of Harbor directly. The
+// credentials are only read from the namespace of the resource, so that it
+// cannot borrow the credentials of another namespace.
+func resolveHarborURL(ctx context.Context, reader client.Reader, discoveryClient discovery.DiscoveryInterface, namespace string, target harborconfigurationv1alpha1.HarborTarget) (harborEndpoint, bool, error) {
+	if target.URL == "" {
+		return harborEndpoint{}, false, nil
+	}
+	if target.CredentialsSecretRef == nil {
+		return harborEndpoint{}, true, errors.New("harborTarget.credentialsSecretRef is required with harborTarget.url")
+	}
+
+	secretRef := harborconfigurationv1alpha1.HarborCredentialsSecretReference{
+		Name:        target.CredentialsSecretRef.Name,
+		Namespace:   namespace,
+		UsernameKey: target.CredentialsSecretRef.UsernameKey,
+		PasswordKey: target.CredentialsSecretRef.PasswordKey,
+	}
+	username, password, err := getHarborCredentials(ctx, reader, secretRef, target.HarborUsername)
+	if err != nil {
+		return harborEndpoint{}, true, err
+	}
+	return harborEndpoint{
+		url:      harborAPIURL(target.URL),
+		username: username,
+		password: password,
+		registry: registryHost(target.URL),
+	}, true, nil
+}
+
+// resolveHarborInstance handles targets referring to a HarborInstance, which
+// only resources in the namespaces it allows may do.
+func resolveHarborInstance(ctx context.Context, reader client.Reader, discoveryClient discovery.DiscoveryInterface, namespace string, target harborconfigurationv1alpha1.HarborTarget) (harborEndpoint, bool, error) {
+	if target.InstanceRef == "" {
+		return harborEndpoint{}, false, nil
+	}
+
+	var instance harborconfigurationv1alpha1.HarborInstance
+	if err := reader.Get(ctx, types.NamespacedName{Name: target.InstanceRef}, &instance); err != nil {
+		return harborEndpoint{}, true, err
+	}
+	if !instance.Spec.AllowsNamespace(namespace) {
+		return harborEndpoint{}, true, fmt.Errorf("HarborInstance %s does not allow resources in namespace %s to target it", instance.Name, namespace)
+	}
+
+	username, password, err := getHarborCredentials(ctx, reader, instance.Spec.CredentialsSecretRef, target.HarborUsername)
+	if err != nil {
+		return harborEndpoint{}, true, err
+	}
+
+	endpoint := harborEndpoint{
+		url:      harborAPIURL(instance.Spec.URL),
+		username: username,
+		password: password,
+		registry: registryHost(instance.Spec.URL),
+		tls: harborTLS{
+			caBundle:           instance.Spec.CABundle,
+			insecureSkipVerify: instance.Spec.InsecureSkipVerify,
+		},
+	}
+	if instance.Spec.Timeout != nil {
+		endpoint.timeout = instance.Spec.Timeout.Duration
+	}
+	return endpoint, true, nil
+}
+
+// resolveHarborCluster handles targets naming a HarborCluster deployed by
+// harbor-operator.
+func resolveHarborCluster(ctx context.Context, reader client.Reader, discoveryClient discovery.DiscoveryInterface, namespace string, target harborconfigurationv1alpha1.HarborTarget) (harborEndpoint, bool, error) {
+	if target.Name == "" {
+		return harborEndpoint{}, false, nil
+	}
+
+	harborClusterGVR, err := harborClusterVersion.get(discoveryClient, harborClusterResource)
+	if err != nil {
+		return harborEndpoint{}, true, err
+	}
+
+	var harborTarget harborOperator.HarborCluster
+	harborTarget, err = getConcreteHarborType(ctx, reader, harborClusterGVR, types.NamespacedName{Namespace: target.Namespace, Name: target.Name}, harborTarget)
+	if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		// The version may no longer be served after harbor-operator was
+		// upgraded, so discover it again next time.
+		harborClusterVersion.forget()
+	}
+	if err != nil {
+		return harborEndpoint{}, true, err
+	}
+
+	haborSecret, err := getHarborSecret(ctx, reader, &harborTarget)
+	if err != nil {
+		return harborEndpoint{}, true, err
+	}
+
+	return harborEndpoint{
+		url:      getHarborURL(&harborTarget),
+		username: target.HarborUsername,
+		password: haborSecret,
+		registry: registryHost(harborTarget.Spec.ExternalURL),
+	}, true, nil
+}
+
+// getHarborCredentials reads the Harbor credentials from the referenced
+// Secret, falling back to username when the Secret holds no user name.
+func getHarborCredentials(ctx context.Context, reader client.Reader, secretRef harborconfigurationv1alpha1.HarborCredentialsSecretReference, username string) (string, string, error) {
+	usernameKey, passwordKey := secretRef.UsernameKey, secretRef.PasswordKey
+	if usernameKey == "" {
+		usernameKey = "username"
+	}
+	if passwordKey == "" {
+		passwordKey = "password"
+	}
+
+	var secret corev1.Secret
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: secretRef.Namespace, Name: secretRef.Name}, &secret); err != nil {
+		return "", "", err
+	}
+	password := string(secret.Data[passwordKey])
+	if password == "" {
+		return "", "", fmt.Errorf("no key %q found in Secret %s/%s", passwordKey, secretRef.Namespace, secretRef.Name)
+	}
+	if secretUsername := string(secret.Data[usernameKey]); secretUsername != "" {
+		username = secretUsername
+	}
+	return username, password, nil
+}
+
+// getTargetTLS reads the TLS settings of the target of a resource in namespace
+// into settings, overriding the ones of the resolved endpoint.
+func getTargetTLS(ctx context.Context, reader client.Reader, namespace string, targetTLS harborconfigurationv1alpha1.HarborTLS, settings *harborTLS) error {
+	if caRef := targetTLS.CARef; caRef != nil {
+		key := caRef.Key
+		if key == "" {
+			key = "ca.crt"
+		}
+
+		kind := caRef.Kind
+		if kind == "" {
+			kind = "ConfigMap"
+		}
+
+		var caBundle string
+		if kind == "Secret" {
+			var secret corev1.Secret
+			if err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: caRef.Name}, &secret); err != nil {
+				return err
+			}
+			caBundle = string(secret.Data[key])
+		} else {
+			var configMap corev1.ConfigMap
+			if err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: caRef.Name}, &configMap); err != nil {
+				return err
+			}
+			caBundle = configMap.Data[key]
+		}
+		if caBundle == "" {
+			return fmt.Errorf("no key %q found in %s %s/%s", key, kind, namespace, caRef.Name)
+		}
+		settings.caBundle = caBundle
+	}
+
+	if secretRef := targetTLS.ClientCertificateSecretRef; secretRef != nil {
+		var secret corev1.Secret
+		if err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: secretRef.Name}, &secret); err != nil {
+			return err
+		}
+		settings.certificate = string(secret.Data[corev1.TLSCertKey])
+		settings.key = string(secret.Data[corev1.TLSPrivateKeyKey])
+		if settings.certificate == "" || settings.key == "" {
+			return fmt.Errorf("no client certificate found in Secret %s/%s", namespace, secretRef.Name)
+		}
+	}
+
+	if targetTLS.ServerName != "" {
+		settings.serverName = targetTLS.ServerName
+	}
+	if targetTLS.InsecureSkipVerify {
+		settings.insecureSkipVerify = true
+	}
+	return nil
+}
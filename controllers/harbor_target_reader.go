@@ -0,0 +1,131 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// harborTargetReader reads the objects Harbor targets are resolved from.
+// HarborClusters and HarborInstances are read from the informer cache. Secrets
+// and ConfigMaps are only cached by their metadata, so that the data of every
+// Secret and ConfigMap in the cluster is not kept in memory: the data of the
+// few ones targets refer to is read from the API server when their
+// resourceVersion changes.
+type harborTargetReader struct {
+	cache  client.Reader
+	api    client.Reader
+	scheme *runtime.Scheme
+
+	mu      sync.Mutex
+	objects map[targetObjectKey]client.Object
+}
+
+type targetObjectKey struct {
+	gvk schema.GroupVersionKind
+	key types.NamespacedName
+}
+
+// NewHarborTargetReader returns a reader for NewHarborClientPool reading from
+// cache, which must serve the metadata of Secrets and ConfigMaps, and from api
+// for the data of Secrets and ConfigMaps.
+func NewHarborTargetReader(cache, api client.Reader, scheme *runtime.Scheme) client.Reader {
+	return &harborTargetReader{
+		cache:   cache,
+		api:     api,
+		scheme:  scheme,
+		objects: map[targetObjectKey]client.Object{},
+	}
+}
+
+func (r *harborTargetReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if !metadataCached(obj) {
+		return r.cache.Get(ctx, key, obj, opts...)
+	}
+
+	gvk, err := apiutil.GVKForObject(obj, r.scheme)
+	if err != nil {
+		return err
+	}
+	objectKey := targetObjectKey{gvk: gvk, key: key}
+
+	metadata := &metav1.PartialObjectMetadata{}
+	metadata.SetGroupVersionKind(gvk)
+	if err := r.cache.Get(ctx, key, metadata); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.mu.Lock()
+			delete(r.objects, objectKey)
+			r.mu.Unlock()
+		}
+		return err
+	}
+
+	r.mu.Lock()
+	stored, ok := r.objects[objectKey]
+	r.mu.Unlock()
+	if ok && stored.GetResourceVersion() == metadata.GetResourceVersion() {
+		copyInto(stored, obj)
+		return nil
+	}
+
+	if err := r.api.Get(ctx, key, obj, opts...); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.objects[objectKey] = obj.DeepCopyObject().(client.Object)
+	r.mu.Unlock()
+	return nil
+}
+
+// List reads Secrets and ConfigMaps from the API server and everything else
+// from the cache.
+func (r *harborTargetReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	switch list.(type) {
+	case *corev1.SecretList, *corev1.ConfigMapList:
+		return r.api.List(ctx, list, opts...)
+	}
+	return r.cache.List(ctx, list, opts...)
+}
+
+// copyInto copies a stored Secret or ConfigMap into obj.
+func copyInto(stored, obj client.Object) {
+	switch obj := obj.(type) {
+	case *corev1.Secret:
+		stored.(*corev1.Secret).DeepCopyInto(obj)
+	case *corev1.ConfigMap:
+		stored.(*corev1.ConfigMap).DeepCopyInto(obj)
+	}
+}
+
+// metadataCached reports whether only the metadata of obj is cached.
+func metadataCached(obj client.Object) bool {
+	switch obj.(type) {
+	case *corev1.Secret, *corev1.ConfigMap:
+		return true
+	}
+	return false
+}
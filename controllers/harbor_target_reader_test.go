@@ -0,0 +1,87 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// countingReader counts the Gets sent to the API server.
+type countingReader struct {
+	client.Reader
+	gets int
+}
+
+func (r *countingReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	r.gets++
+	return r.Reader.Get(ctx, key, obj, opts...)
+}
+
+func TestHarborTargetReader(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team", Name: "harbor-admin"},
+		Data:       map[string][]byte{"password": []byte("secret")},
+	}
+	c := fake.NewClientBuilder().WithObjects(secret).Build()
+	api := &countingReader{Reader: c}
+	reader := NewHarborTargetReader(c, api, scheme.Scheme)
+	ctx := context.Background()
+	key := client.ObjectKeyFromObject(secret)
+
+	read := func(want string, wantGets int) {
+		t.Helper()
+		var got corev1.Secret
+		if err := reader.Get(ctx, key, &got); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if string(got.Data["password"]) != want {
+			t.Errorf("Get() password = %q, want %q", got.Data["password"], want)
+		}
+		if api.gets != wantGets {
+			t.Errorf("Get() read from the API server %d times, want %d", api.gets, wantGets)
+		}
+	}
+
+	read("secret", 1)
+	read("secret", 1)
+
+	var stored corev1.Secret
+	if err := c.Get(ctx, key, &stored); err != nil {
+		t.Fatal(err)
+	}
+	stored.Data["password"] = []byte("rotated")
+	if err := c.Update(ctx, &stored); err != nil {
+		t.Fatal(err)
+	}
+	read("rotated", 2)
+	read("rotated", 2)
+
+	if err := c.Delete(ctx, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if err := reader.Get(ctx, key, &corev1.Secret{}); !apierrors.IsNotFound(err) {
+		t.Errorf("Get() of a deleted Secret error = %v, want NotFound", err)
+	}
+}
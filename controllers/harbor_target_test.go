@@ -0,0 +1,349 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+// newTargetScheme returns a scheme knowing the built-in kinds and the ones of
+// the operator.
+func newTargetScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := harborconfigurationv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
+
+func TestResolveHarborInstance(t *testing.T) {
+	reader := fake.NewClientBuilder().WithScheme(newTargetScheme(t)).WithObjects(
+		&harborconfigurationv1alpha1.HarborInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "production"},
+			Spec: harborconfigurationv1alpha1.HarborInstanceSpec{
+				URL:                  "https://harbor.example.com",
+				CredentialsSecretRef: harborconfigurationv1alpha1.HarborCredentialsSecretReference{Name: "harbor-admin", Namespace: "harbor-system"},
+				AllowedNamespaces:    []string{"team-a"},
+			},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "harbor-system", Name: "harbor-admin"},
+			Data:       map[string][]byte{"username": []byte("admin"), "password": []byte("secret")},
+		},
+	).Build()
+	target := harborconfigurationv1alpha1.HarborTarget{InstanceRef: "production"}
+
+	tests := []struct {
+		name      string
+		namespace string
+		wantErr   bool
+	}{
+		{
+			name:      "allowed namespace",
+			namespace: "team-a",
+		},
+		{
+			name:      "namespace of the credentials",
+			namespace: "harbor-system",
+		},
+		{
+			name:      "other namespace",
+			namespace: "team-b",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint, ok, err := resolveHarborInstance(context.Background(), reader, nil, tt.namespace, target)
+			if !ok {
+				t.Fatal("resolveHarborInstance() did not handle the target")
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveHarborInstance() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && endpoint.password != "secret" {
+				t.Errorf("resolveHarborInstance() password = %q, want %q", endpoint.password, "secret")
+			}
+		})
+	}
+}
+
+func TestResolveHarborURL(t *testing.T) {
+	reader := fake.NewClientBuilder().WithScheme(newTargetScheme(t)).WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "harbor-admin"},
+			Data:       map[string][]byte{"username": []byte("admin"), "password": []byte("secret")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "harbor-robot"},
+			Data:       map[string][]byte{"user": []byte("robot$team-a"), "token": []byte("token")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "harbor-password"},
+			Data:       map[string][]byte{"password": []byte("secret")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "harbor-system", Name: "harbor-system-admin"},
+			Data:       map[string][]byte{"username": []byte("admin"), "password": []byte("secret")},
+		},
+	).Build()
+	secretRef := func(name, usernameKey, passwordKey string) *harborconfigurationv1alpha1.LocalHarborCredentialsSecretReference {
+		return &harborconfigurationv1alpha1.LocalHarborCredentialsSecretReference{Name: name, UsernameKey: usernameKey, PasswordKey: passwordKey}
+	}
+
+	tests := []struct {
+		name         string
+		target       harborconfigurationv1alpha1.HarborTarget
+		wantUsername string
+		wantPassword string
+		wantErr      string
+	}{
+		{
+			name:         "credentials",
+			target:       harborconfigurationv1alpha1.HarborTarget{CredentialsSecretRef: secretRef("harbor-admin", "", "")},
+			wantUsername: "admin",
+			wantPassword: "secret",
+		},
+		{
+			name:         "custom keys",
+			target:       harborconfigurationv1alpha1.HarborTarget{CredentialsSecretRef: secretRef("harbor-robot", "user", "token")},
+			wantUsername: "robot$team-a",
+			wantPassword: "token",
+		},
+		{
+			name:         "user name of the target",
+			target:       harborconfigurationv1alpha1.HarborTarget{HarborUsername: "operator", CredentialsSecretRef: secretRef("harbor-password", "", "")},
+			wantUsername: "operator",
+			wantPassword: "secret",
+		},
+		{
+			name:    "missing key",
+			target:  harborconfigurationv1alpha1.HarborTarget{CredentialsSecretRef: secretRef("harbor-robot", "", "")},
+			wantErr: `no key "password" found in Secret team-a/harbor-robot`,
+		},
+		{
+			name:    "credentials in another namespace",
+			target:  harborconfigurationv1alpha1.HarborTarget{CredentialsSecretRef: secretRef("harbor-system-admin", "", "")},
+			wantErr: "not found",
+		},
+		{
+			name:    "no credentials",
+			wantErr: "credentialsSecretRef is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := tt.target
+			target.URL = "https://harbor.example.com/"
+			endpoint, ok, err := resolveHarborURL(context.Background(), reader, nil, "team-a", target)
+			if !ok {
+				t.Fatal("resolveHarborURL() did not handle the target")
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("resolveHarborURL() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveHarborURL() error = %v", err)
+			}
+			if endpoint.username != tt.wantUsername || endpoint.password != tt.wantPassword {
+				t.Errorf("resolveHarborURL() credentials = %q, %q, want %q, %q", endpoint.username, endpoint.password, tt.wantUsername, tt.wantPassword)
+			}
+			if endpoint.url != "https://harbor.example.com/api/v2.0" || endpoint.registry != "harbor.example.com" {
+				t.Errorf("resolveHarborURL() url = %q, registry = %q", endpoint.url, endpoint.registry)
+			}
+		})
+	}
+
+	if _, ok, _ := resolveHarborURL(context.Background(), reader, nil, "team-a", harborconfigurationv1alpha1.HarborTarget{Name: "harbor"}); ok {
+		t.Error("resolveHarborURL() handled a target without url")
+	}
+}
+
+func TestGetTargetTLS(t *testing.T) {
+	reader := fake.NewClientBuilder().WithScheme(newTargetScheme(t)).WithObjects(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "harbor-ca"},
+			Data:       map[string]string{"ca.crt": "config map bundle"},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "harbor-ca"},
+			Data:       map[string][]byte{"bundle.pem": []byte("secret bundle")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "harbor-client"},
+			Data:       map[string][]byte{corev1.TLSCertKey: []byte("certificate"), corev1.TLSPrivateKeyKey: []byte("key")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "harbor-certificate-only"},
+			Data:       map[string][]byte{corev1.TLSCertKey: []byte("certificate")},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "harbor-system", Name: "harbor-system-ca"},
+			Data:       map[string]string{"ca.crt": "bundle"},
+		},
+	).Build()
+
+	tests := []struct {
+		name    string
+		tls     harborconfigurationv1alpha1.HarborTLS
+		want    harborTLS
+		wantErr string
+	}{
+		{
+			name: "config map",
+			tls:  harborconfigurationv1alpha1.HarborTLS{CARef: &harborconfigurationv1alpha1.CABundleRef{Name: "harbor-ca"}},
+			want: harborTLS{caBundle: "config map bundle"},
+		},
+		{
+			name: "secret",
+			tls:  harborconfigurationv1alpha1.HarborTLS{CARef: &harborconfigurationv1alpha1.CABundleRef{Kind: "Secret", Name: "harbor-ca", Key: "bundle.pem"}},
+			want: harborTLS{caBundle: "secret bundle"},
+		},
+		{
+			name: "client certificate",
+			tls: harborconfigurationv1alpha1.HarborTLS{
+				ClientCertificateSecretRef: &harborconfigurationv1alpha1.TLSSecretRef{Name: "harbor-client"},
+				ServerName:                 "harbor.internal",
+			},
+			want: harborTLS{certificate: "certificate", key: "key", serverName: "harbor.internal"},
+		},
+		{
+			name:    "missing config map",
+			tls:     harborconfigurationv1alpha1.HarborTLS{CARef: &harborconfigurationv1alpha1.CABundleRef{Name: "other-ca"}},
+			wantErr: "not found",
+		},
+		{
+			name:    "config map in another namespace",
+			tls:     harborconfigurationv1alpha1.HarborTLS{CARef: &harborconfigurationv1alpha1.CABundleRef{Name: "harbor-system-ca"}},
+			wantErr: "not found",
+		},
+		{
+			name:    "missing key",
+			tls:     harborconfigurationv1alpha1.HarborTLS{CARef: &harborconfigurationv1alpha1.CABundleRef{Name: "harbor-ca", Key: "bundle.pem"}},
+			wantErr: `no key "bundle.pem" found in ConfigMap team-a/harbor-ca`,
+		},
+		{
+			name:    "missing secret key",
+			tls:     harborconfigurationv1alpha1.HarborTLS{CARef: &harborconfigurationv1alpha1.CABundleRef{Kind: "Secret", Name: "harbor-ca"}},
+			wantErr: `no key "ca.crt" found in Secret team-a/harbor-ca`,
+		},
+		{
+			name:    "client certificate without key",
+			tls:     harborconfigurationv1alpha1.HarborTLS{ClientCertificateSecretRef: &harborconfigurationv1alpha1.TLSSecretRef{Name: "harbor-certificate-only"}},
+			wantErr: "no client certificate found in Secret team-a/harbor-certificate-only",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var settings harborTLS
+			err := getTargetTLS(context.Background(), reader, "team-a", tt.tls, &settings)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("getTargetTLS() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("getTargetTLS() error = %v", err)
+			}
+			if settings != tt.want {
+				t.Errorf("getTargetTLS() = %+v, want %+v", settings, tt.want)
+			}
+		})
+	}
+}
+
+// failingReader fails every Get with err, recording the requested kind.
+type failingReader struct {
+	client.Reader
+	err       error
+	requested schema.GroupVersionKind
+}
+
+func (r *failingReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	r.requested = obj.GetObjectKind().GroupVersionKind()
+	return r.err
+}
+
+func TestResolveHarborCluster(t *testing.T) {
+	t.Cleanup(harborClusterVersion.forget)
+	discoveryClient := newFakeDiscovery(harborClusterResourceList("goharbor.io/v1beta1", "harborclusters"))
+	target := harborconfigurationv1alpha1.HarborTarget{Name: "harbor", Namespace: "harbor-system", HarborUsername: "admin"}
+
+	tests := []struct {
+		name       string
+		err        error
+		wantForget bool
+	}{
+		{
+			name:       "not found",
+			err:        apierrors.NewNotFound(schema.GroupResource{Group: "goharbor.io", Resource: "harborclusters"}, "harbor"),
+			wantForget: true,
+		},
+		{
+			name:       "no match",
+			err:        &meta.NoKindMatchError{GroupKind: schema.GroupKind{Group: "goharbor.io", Kind: "HarborCluster"}, SearchedVersions: []string{"v1alpha3"}},
+			wantForget: true,
+		},
+		{
+			name: "other error",
+			err:  apierrors.NewServiceUnavailable("etcd is unavailable"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			harborClusterVersion.version = "v1alpha3"
+			_, ok, err := resolveHarborCluster(context.Background(), &failingReader{err: tt.err}, discoveryClient, "team-a", target)
+			if !ok || err == nil {
+				t.Fatalf("resolveHarborCluster() = %v, %v, want the error of the Get", ok, err)
+			}
+			if forgotten := harborClusterVersion.version == ""; forgotten != tt.wantForget {
+				t.Errorf("resolveHarborCluster() forgot the version = %v, want %v", forgotten, tt.wantForget)
+			}
+		})
+	}
+
+	t.Run("rediscovers the version", func(t *testing.T) {
+		harborClusterVersion.forget()
+		reader := &failingReader{err: apierrors.NewServiceUnavailable("etcd is unavailable")}
+		_, _, _ = resolveHarborCluster(context.Background(), reader, discoveryClient, "team-a", target)
+		if reader.requested.Version != "v1beta1" || harborClusterVersion.version != "v1beta1" {
+			t.Errorf("resolveHarborCluster() read %v and cached %q, want v1beta1", reader.requested, harborClusterVersion.version)
+		}
+	})
+}
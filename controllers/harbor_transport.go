@@ -0,0 +1,165 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// harborEndpointHeader tells harborTransports which Harbor API client a
+// request sent by goharbor-client belongs to. It is removed before the request
+// leaves the process.
+const harborEndpointHeader = "X-Harbor-Config-Operator-Client"
+
+// harborTransports routes the requests of goharbor-client to the transport of
+// their Harbor API client. goharbor-client sends its requests through the
+// transport http.DefaultTransport holds when a client is built, and offers no
+// way to pass one in, so harborTransports takes the place of
+// http.DefaultTransport. Requests that do not belong to a Harbor API client go
+// through the original default transport.
+var harborTransports = &endpointTransports{
+	fallback:   http.DefaultTransport.(*http.Transport),
+	transports: map[string]http.RoundTripper{},
+}
+
+func init() {
+	http.DefaultTransport = harborTransports
+}
+
+// endpointTransports maps Harbor API clients to their transport.
+type endpointTransports struct {
+	fallback *http.Transport
+
+	mu         sync.RWMutex
+	lastID     uint64
+	transports map[string]http.RoundTripper
+}
+
+// add registers the transport of a Harbor API client and returns the ID its
+// requests are sent with.
+func (t *endpointTransports) add(transport http.RoundTripper) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastID++
+	id := strconv.FormatUint(t.lastID, 10)
+	t.transports[id] = transport
+	return id
+}
+
+// remove forgets the transport of a Harbor API client that is no longer used.
+func (t *endpointTransports) remove(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.transports, id)
+}
+
+func (t *endpointTransports) RoundTrip(req *http.Request) (*http.Response, error) {
+	id := req.Header.Get(harborEndpointHeader)
+	if id == "" {
+		return t.fallback.RoundTrip(req)
+	}
+
+	t.mu.RLock()
+	transport, ok := t.transports[id]
+	t.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("the Harbor API client for %s was closed", req.URL.Host)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Del(harborEndpointHeader)
+	return transport.RoundTrip(req)
+}
+
+// CloseIdleConnections closes the idle connections of the original default
+// transport. Harbor API clients close their own.
+func (t *endpointTransports) CloseIdleConnections() {
+	t.fallback.CloseIdleConnections()
+}
+
+// harborTLS are the TLS settings of a Harbor instance.
+type harborTLS struct {
+	caBundle           string
+	certificate        string
+	key                string
+	serverName         string
+	insecureSkipVerify bool
+}
+
+// newHarborHTTPClient builds the HTTP client of a Harbor endpoint. Every
+// client has a transport of its own, so the TLS settings of a target never
+// apply to another target, even on the same host.
+func newHarborHTTPClient(settings harborTLS) (*http.Client, error) {
+	transport := harborTransports.fallback.Clone()
+	if settings != (harborTLS{}) {
+		tlsConfig, err := settings.config()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	return &http.Client{Transport: observedTransport{next: transport}}, nil
+}
+
+// config builds the TLS configuration of the settings.
+func (settings harborTLS) config() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         settings.serverName,
+		InsecureSkipVerify: settings.insecureSkipVerify,
+	}
+	if settings.caBundle != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(settings.caBundle)) {
+			return nil, errors.New("no certificate found in the CA bundle")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if settings.certificate != "" {
+		certificate, err := tls.X509KeyPair([]byte(settings.certificate), []byte(settings.key))
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
+
+// observedTransport records the requests to Harbor.
+type observedTransport struct {
+	next *http.Transport
+}
+
+func (t observedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	observeHarborRequest(req, resp, err, time.Since(start))
+	return resp, err
+}
+
+// CloseIdleConnections lets http.Client.CloseIdleConnections reach the
+// underlying transport.
+func (t observedTransport) CloseIdleConnections() {
+	t.next.CloseIdleConnections()
+}
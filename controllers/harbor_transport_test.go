@@ -0,0 +1,113 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewHarborClientTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2.0/ping" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get(harborEndpointHeader) != "" {
+			http.Error(w, "client header sent to Harbor", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("Pong"))
+	}))
+	defer server.Close()
+
+	caBundle := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	tests := []struct {
+		name    string
+		tls     harborTLS
+		wantErr bool
+	}{
+		{
+			name:    "system roots",
+			wantErr: true,
+		},
+		{
+			name: "CA bundle",
+			tls:  harborTLS{caBundle: caBundle},
+		},
+		{
+			name: "insecure",
+			tls:  harborTLS{insecureSkipVerify: true},
+		},
+		{
+			name:    "wrong server name",
+			tls:     harborTLS{caBundle: caBundle, serverName: "harbor.invalid"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := newHarborClient(harborEndpoint{
+				url:      harborAPIURL(server.URL),
+				username: "admin",
+				password: "secret",
+				tls:      tt.tls,
+			})
+			if err != nil {
+				t.Fatalf("newHarborClient() error = %v", err)
+			}
+			defer client.close()
+
+			_, err = client.GetPing(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetPing() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewHarborHTTPClientInvalidCA(t *testing.T) {
+	if _, err := newHarborHTTPClient(harborTLS{caBundle: "not a certificate"}); err == nil {
+		t.Error("newHarborHTTPClient() error = nil, want an error")
+	}
+}
+
+func TestHarborTransportsClosedClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("Pong"))
+	}))
+	defer server.Close()
+
+	client, err := newHarborClient(harborEndpoint{url: harborAPIURL(server.URL), username: "admin", password: "secret"})
+	if err != nil {
+		t.Fatalf("newHarborClient() error = %v", err)
+	}
+	if _, err := client.GetPing(context.Background()); err != nil {
+		t.Fatalf("GetPing() error = %v", err)
+	}
+
+	client.close()
+	if _, err := client.GetPing(context.Background()); err == nil {
+		t.Error("GetPing() on a closed client error = nil, want an error")
+	}
+}
@@ -21,34 +21,40 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	chain "github.com/g8rswimmer/error-chain"
-	harborOperator "github.com/goharbor/harbor-operator/apis/goharbor.io/v1beta1"
 	"github.com/goharbor/harbor-operator/pkg/cluster/k8s"
-	apiv2 "github.com/mittwald/goharbor-client/v5/apiv2"
 	modelv2 "github.com/mittwald/goharbor-client/v5/apiv2/model"
-	rep "github.com/mittwald/goharbor-client/v5/apiv2/pkg/clients/replication"
 	harborerrors "github.com/mittwald/goharbor-client/v5/apiv2/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/client-go/dynamic"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	controllerutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
 )
 
-var (
-	harborClusterGVM = schema.GroupVersionResource{
-		Group:    "goharbor.io",
-		Version:  "v1alpha3",
-		Resource: "harborclusters",
-	}
+const (
+	harborFinaliserName      = "administration.harbor.configuration/finalizer"
+	credentialSecretRefField = ".spec.registry.credential.secretRef"
+	// legacyAdoptionAnnotation marks a HarborConfiguration reconciled before
+	// ownership markers were introduced until every Harbor object it declares
+	// has been adopted.
+	legacyAdoptionAnnotation = "administration.harbor.configuration/legacy-adoption"
 )
 
 // HarborConfigurationReconciler reconciles a HarborConfiguration object
@@ -56,17 +62,22 @@ type HarborConfigurationReconciler struct {
 	DClient *k8s.DynamicClientWrapper
 	client.Client
 	*runtime.Scheme
-	ClientSet  *kubernetes.Clientset
-	DynamicSet dynamic.Interface
+	ClientSet     *kubernetes.Clientset
+	HarborClients *HarborClientPool
+	Recorder      record.EventRecorder
+	// ResyncInterval is how often Harbor is checked for drift from the spec.
+	ResyncInterval time.Duration
 }
 
 //+kubebuilder:rbac:groups=administration.harbor.configuration,resources=harborconfigurations,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=administration.harbor.configuration,resources=harborconfigurations/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=administration.harbor.configuration,resources=harborconfigurations/finalizers,verbs=update
+//+kubebuilder:rbac:groups=administration.harbor.configuration,resources=harborinstances,verbs=get;list;watch
 //+kubebuilder:rbac:groups=goharbor.io,resources=harborclusters,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=goharbor.io,resources=harborclusters/status,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=goharbor.io,resources=harborclusters/finalizers,verbs=get;list;watch;create;update;patch;delete
-//+kubebuilder:rbac:groups="",resources=secrets;services,verbs=get;list
+//+kubebuilder:rbac:groups="",resources=configmaps;secrets;services,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 func (r *HarborConfigurationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	_ = log.FromContext(ctx)
@@ -74,368 +85,964 @@ func (r *HarborConfigurationReconciler) Reconcile(ctx context.Context, req ctrl.
 	var harborConfiguration harborconfigurationv1alpha1.HarborConfiguration
 	err := r.Get(ctx, req.NamespacedName, &harborConfiguration)
 	if err != nil {
-		return ctrl.Result{}, err
+		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	requestResource := r.DynamicSet.Resource(harborClusterGVM).Namespace(harborConfiguration.Spec.HarborTarget.Namespace)
-	if err != nil {
-		return ctrl.Result{}, err
+	if !harborConfiguration.ObjectMeta.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(&harborConfiguration, harborFinaliserName) {
+			return ctrl.Result{}, nil
+		}
+
+		client, err := r.HarborClients.Get(ctx, harborConfiguration.Namespace, harborConfiguration.Spec.HarborTarget)
+		if err != nil {
+			events := harborEvents{recorder: r.Recorder, object: &harborConfiguration}
+			return finalizeWithoutHarbor(ctx, r.Client, events, &harborConfiguration, orphansAll(&harborConfiguration), err)
+		}
+		if _, err := deleteAll(ctx, r.Recorder, harborConfiguration, client); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, removeFinalizer(ctx, r.Client, &harborConfiguration)
 	}
 
-	var harborTarget harborOperator.HarborCluster
-	harborTarget, err = getConcreteHarborType(ctx, requestResource, harborConfiguration, harborTarget)
-	if err != nil {
-		return ctrl.Result{}, err
+	// The legacy adoption is recorded before any status is written, as the
+	// status alone no longer tells legacy resources apart afterwards.
+	legacy := adoptsLegacyObjects(&harborConfiguration)
+	if legacy {
+		if err := markLegacyAdoption(ctx, r.Client, &harborConfiguration); err != nil {
+			return ctrl.Result{}, err
+		}
 	}
 
-	haborSecret, err := getHarborSecret(ctx, r.ClientSet, &harborTarget)
+	client, err := r.HarborClients.Get(ctx, harborConfiguration.Namespace, harborConfiguration.Spec.HarborTarget)
 	if err != nil {
+		harborEvents{recorder: r.Recorder, object: &harborConfiguration}.harborUnavailable(harborConfiguration.Status.Conditions, err)
+		setCondition(&harborConfiguration, harborconfigurationv1alpha1.ConditionReady, v1.ConditionFalse, harborconfigurationv1alpha1.ReasonHarborUnavailable, err.Error())
+		if statusErr := r.updateStatus(ctx, &harborConfiguration); statusErr != nil {
+			return ctrl.Result{}, statusErr
+		}
 		return ctrl.Result{}, err
 	}
 
-	client, err := apiv2.NewRESTClientForHost(getHarborURL(&harborTarget), harborConfiguration.Spec.HarborTarget.HarborUsername, haborSecret, nil)
-	if err != nil {
+	if err := addFinalizer(ctx, r.Client, &harborConfiguration); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	harborFinaliserName := "administration.harbor.configuration/finalizer"
-
-	if harborConfiguration.ObjectMeta.DeletionTimestamp.IsZero() {
-		if !controllerutil.ContainsFinalizer(&harborConfiguration, harborFinaliserName) {
-			controllerutil.AddFinalizer(&harborConfiguration, harborFinaliserName)
-			if err := r.Update(ctx, &harborConfiguration); err != nil {
-				return ctrl.Result{}, err
-			}
-		}
+	result, err := r.reconcileAll(ctx, &harborConfiguration, legacy, client)
+	if err == nil {
+		setCondition(&harborConfiguration, harborconfigurationv1alpha1.ConditionReady, v1.ConditionTrue, harborconfigurationv1alpha1.ReasonReconciled, "")
+	}
 
-		_, err = r.reconcileAll(ctx, harborConfiguration, client)
-		if err != nil {
+	if statusErr := r.updateStatus(ctx, &harborConfiguration); statusErr != nil && err == nil {
+		return ctrl.Result{}, statusErr
+	}
+	if err == nil && legacy {
+		if err := finishLegacyAdoption(ctx, r.Client, &harborConfiguration); err != nil {
 			return ctrl.Result{}, err
 		}
+	}
+	// An execution still in progress is polled until it finishes.
+	return resyncAfter(result, r.ResyncInterval), err
+}
 
-		_, err = triggerReplication(ctx, harborConfiguration, client)
-		if err != nil {
-			return ctrl.Result{}, err
-		}
-	} else {
+// setItemsCondition sets the given condition from the errors collected while
+// reconciling the items it covers.
+func setItemsCondition(harborConfiguration *harborconfigurationv1alpha1.HarborConfiguration, conditionType string, itemErrors *chain.ErrorChain) {
+	if len(itemErrors.Errors()) > 0 {
+		setCondition(harborConfiguration, conditionType, v1.ConditionFalse, harborconfigurationv1alpha1.ReasonReconcileFailed, itemErrors.Error())
+		return
+	}
+	setCondition(harborConfiguration, conditionType, v1.ConditionTrue, harborconfigurationv1alpha1.ReasonReconciled, "")
+}
 
-		if controllerutil.ContainsFinalizer(&harborConfiguration, harborFinaliserName) {
-			_, err = deleteAll(ctx, harborConfiguration, client)
-			if err != nil {
-				return ctrl.Result{}, err
-			}
-			controllerutil.RemoveFinalizer(&harborConfiguration, harborFinaliserName)
-			if err := r.Update(ctx, &harborConfiguration); err != nil {
-				return ctrl.Result{}, err
-			}
-		}
+// updateStatus records the observed generation and writes the status subresource.
+func (r *HarborConfigurationReconciler) updateStatus(ctx context.Context, harborConfiguration *harborconfigurationv1alpha1.HarborConfiguration) error {
+	harborConfiguration.Status.ObservedGeneration = harborConfiguration.Generation
+	managed := map[string]int{"registry": 0, "project": 0, "replication": 0}
+	for key := range knownIDs(harborConfiguration) {
+		managed[strings.SplitN(key, "/", 2)[0]]++
 	}
-	return ctrl.Result{}, nil
+	recordResourceStatus(harborConfiguration, harborConfiguration.Status.Conditions, managed)
+	return r.Status().Update(ctx, harborConfiguration)
+}
+
+// setCondition sets the given condition on the HarborConfiguration status,
+// stamping it with the generation currently being reconciled.
+func setCondition(harborConfiguration *harborconfigurationv1alpha1.HarborConfiguration, conditionType string, status v1.ConditionStatus, reason, message string) {
+	setStatusCondition(&harborConfiguration.Status.Conditions, harborConfiguration.Generation, conditionType, status, reason, message)
+}
+
+func setStatusCondition(conditions *[]v1.Condition, generation int64, conditionType string, status v1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(conditions, v1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: generation,
+	})
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *HarborConfigurationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	err := mgr.GetFieldIndexer().IndexField(context.Background(), &harborconfigurationv1alpha1.HarborConfiguration{}, credentialSecretRefField, func(obj client.Object) []string {
+		harborConfiguration := obj.(*harborconfigurationv1alpha1.HarborConfiguration)
+		var secretRefs []string
+		for _, registry := range harborConfiguration.Spec.AllRegistries() {
+			if registry.Credential == nil || registry.Credential.SecretRef == nil {
+				continue
+			}
+			secretRefs = append(secretRefs, types.NamespacedName{
+				Namespace: harborConfiguration.Namespace,
+				Name:      registry.Credential.SecretRef.Name,
+			}.String())
+		}
+		return secretRefs
+	})
+	if err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&harborconfigurationv1alpha1.HarborConfiguration{}).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.findConfigurationsForSecret), builder.OnlyMetadata).
 		Complete(r)
 }
 
-func (r *HarborConfigurationReconciler) registryReconciliation(ctx context.Context, harborConfiguration harborconfigurationv1alpha1.HarborConfiguration, registry modelv2.Registry, client *apiv2.RESTClient) (ctrl.Result, error) {
-	err := client.NewRegistry(ctx, &registry)
-	if errors.Is(err, &harborerrors.ErrRegistryNameAlreadyExists{}) {
-		update := &modelv2.RegistryUpdate{
-			Name:        &harborConfiguration.Spec.Registry.Name,
-			URL:         &harborConfiguration.Spec.Registry.EndpointUrl,
-			Description: &harborConfiguration.Spec.Registry.Description,
+// findConfigurationsForSecret maps a Secret to the HarborConfigurations
+// whose registry credential references it.
+func (r *HarborConfigurationReconciler) findConfigurationsForSecret(secret client.Object) []reconcile.Request {
+	return listRequests(r.Client, &harborconfigurationv1alpha1.HarborConfigurationList{}, client.MatchingFields{
+		credentialSecretRefField: client.ObjectKeyFromObject(secret).String(),
+	})
+}
+
+// registryReconciliation creates the registry or brings it back in line with
+// the spec, returning the fields that had drifted in Harbor.
+func registryReconciliation(ctx context.Context, registry modelv2.Registry, owner ownership, client *harborClient) ([]string, error) {
+	description := registry.Description
+	registry.Description = withOwner(description, owner.Owner)
+
+	srcRegistry, err := client.GetRegistryByName(ctx, registry.Name)
+	if errors.Is(err, &harborerrors.ErrRegistryNotFound{}) {
+		return []string{driftDeleted}, client.NewRegistry(ctx, &registry)
+	}
+	if err != nil {
+		return nil, err
+	}
+	currentOwner := describedOwner(srcRegistry.Description)
+	if err := owner.claim("registry", registry.Name, srcRegistry.ID, currentOwner); err != nil {
+		return nil, err
+	}
+
+	var drifted []string
+	if strings.TrimSuffix(srcRegistry.URL, "/") != strings.TrimSuffix(registry.URL, "/") {
+		drifted = append(drifted, fieldChange("url", srcRegistry.URL, registry.URL))
+	}
+	if stripOwner(srcRegistry.Description) != description {
+		drifted = append(drifted, "description")
+	}
+	// Harbor never returns the access secret, so a configured credential is
+	// written on every reconciliation instead of being compared.
+	if len(drifted) == 0 && currentOwner == owner.Owner && registry.Credential == nil {
+		return nil, nil
+	}
+
+	update := &modelv2.RegistryUpdate{
+		Name:        &registry.Name,
+		URL:         &registry.URL,
+		Description: &registry.Description,
+	}
+	if registry.Credential != nil {
+		update.AccessKey = &registry.Credential.AccessKey
+		update.AccessSecret = &registry.Credential.AccessSecret
+		update.CredentialType = &registry.Credential.Type
+	}
+	err = client.UpdateRegistry(ctx, update, srcRegistry.ID)
+	if err != nil {
+		return nil, err
+	}
+	return drifted, nil
+}
+
+// projectReconciliation creates the project or brings it back in line with
+// the spec, returning the fields that had drifted in Harbor.
+func projectReconciliation(ctx context.Context, project harborconfigurationv1alpha1.ProjectReq, owner ownership, client *harborClient) ([]string, error) {
+	var registryID int64
+	if project.ProxyCacheRegistryName != "" {
+		srcRegistry, err := client.GetRegistryByName(ctx, project.ProxyCacheRegistryName)
+		if err != nil {
+			return nil, err
+		}
+		registryID = srcRegistry.ID
+	}
+
+	existingProject, err := client.GetProject(ctx, project.ProjectName)
+	if errors.Is(err, &harborerrors.ErrProjectNotFound{}) {
+		requestedProject := &modelv2.ProjectReq{
+			ProjectName:  project.ProjectName,
+			Public:       project.Public,
+			StorageLimit: project.StorageQuota,
 		}
-		if harborConfiguration.Spec.Registry.Credential != nil {
-			update.AccessKey = &harborConfiguration.Spec.Registry.Credential.AccessKey
-			update.AccessSecret = &harborConfiguration.Spec.Registry.Credential.AccessSecret
-			update.CredentialType = &harborConfiguration.Spec.Registry.Credential.Type
+		if registryID != 0 {
+			requestedProject.RegistryID = &registryID
 		}
-		srcRegistry, err := client.GetRegistryByName(ctx, harborConfiguration.Spec.Registry.Name)
+		if project.Metadata != nil {
+			requestedProject.Metadata = &modelv2.ProjectMetadata{}
+			applyProjectMetadata(requestedProject.Metadata, project.Metadata)
+		}
+		err = client.NewProject(ctx, requestedProject)
 		if err != nil {
-			return ctrl.Result{}, err
+			return nil, err
+		}
+		existingProject, err = client.GetProject(ctx, project.ProjectName)
+		if err != nil {
+			return nil, err
 		}
+		err = setProjectOwner(ctx, existingProject.ProjectID, owner.Owner, client)
+		if err != nil {
+			return nil, err
+		}
+		_, err = projectMembersReconciliation(ctx, project.ProjectName, project.Members, client)
+		return []string{driftDeleted}, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	currentOwner, err := projectOwner(ctx, existingProject.ProjectID, client)
+	if err != nil {
+		return nil, err
+	}
+	if err := owner.claim("project", project.ProjectName, int64(existingProject.ProjectID), currentOwner); err != nil {
+		return nil, err
+	}
+	if currentOwner != owner.Owner {
+		err = setProjectOwner(ctx, existingProject.ProjectID, owner.Owner, client)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-		err = client.UpdateRegistry(ctx, update, srcRegistry.ID)
+	var drifted []string
+	metadata := &modelv2.ProjectMetadata{}
+	if existingProject.Metadata != nil {
+		*metadata = *existingProject.Metadata
+	}
+	if project.Public != nil && metadata.Public != strconv.FormatBool(*project.Public) {
+		drifted = append(drifted, fieldChange("public", metadata.Public, *project.Public))
+		metadata.Public = strconv.FormatBool(*project.Public)
+	}
+	if existingProject.RegistryID != registryID {
+		drifted = append(drifted, fieldChange("proxyCacheRegistry", existingProject.RegistryID, registryID))
+	}
+	drifted = append(drifted, applyProjectMetadata(metadata, project.Metadata)...)
+	if len(drifted) > 0 {
+		err = client.UpdateProject(ctx, &modelv2.Project{
+			Name:       project.ProjectName,
+			ProjectID:  existingProject.ProjectID,
+			RegistryID: registryID,
+			Metadata:   metadata,
+		}, nil)
 		if err != nil {
-			return ctrl.Result{}, err
+			return nil, err
 		}
-	} else {
-		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
-}
+	if project.StorageQuota != nil {
+		// Harbor treats every value below one as unlimited.
+		storageLimit := *project.StorageQuota
+		if storageLimit <= 0 {
+			storageLimit = -1
+		}
+		quota, err := client.GetQuotaByProjectID(ctx, int64(existingProject.ProjectID))
+		if err != nil {
+			return nil, err
+		}
+		if quota.Hard["storage"] != storageLimit {
+			err = client.UpdateStorageQuotaByProjectID(ctx, int64(existingProject.ProjectID), storageLimit)
+			if err != nil {
+				return nil, err
+			}
+			drifted = append(drifted, fieldChange("storageQuota", quota.Hard["storage"], storageLimit))
+		}
+	}
 
-func (r *HarborConfigurationReconciler) projectReconciliation(ctx context.Context, harborConfiguration harborconfigurationv1alpha1.HarborConfiguration, registry modelv2.Registry, client *apiv2.RESTClient) (ctrl.Result, error) {
-	srcRegistry, err := client.GetRegistryByName(ctx, harborConfiguration.Spec.ProjectReq.ProxyCacheRegistryName)
+	changedMembers, err := projectMembersReconciliation(ctx, project.ProjectName, project.Members, client)
 	if err != nil {
-		return ctrl.Result{}, err
+		return nil, err
 	}
+	return append(drifted, changedMembers...), nil
+}
 
-	requestedProject := &modelv2.ProjectReq{
-		ProjectName:  harborConfiguration.Spec.ProjectReq.ProjectName,
-		Public:       harborConfiguration.Spec.ProjectReq.Public,
-		StorageLimit: harborConfiguration.Spec.ProjectReq.StorageQuota,
-		RegistryID:   &srcRegistry.ID,
+// applyProjectMetadata sets the metadata requested in the spec, returning
+// the fields that differed.
+func applyProjectMetadata(metadata *modelv2.ProjectMetadata, requested *harborconfigurationv1alpha1.ProjectMetadata) []string {
+	if requested == nil {
+		return nil
 	}
 
-	err = client.NewProject(ctx, requestedProject)
-	if errors.Is(err, &harborerrors.ErrProjectNameAlreadyExists{}) {
-		existingProject, err := client.GetProject(ctx, requestedProject.ProjectName)
-		if err != nil {
-			return ctrl.Result{}, err
+	var drifted []string
+	apply := func(field string, existing **string, value string) {
+		// Harbor leaves out the settings that were never enabled.
+		current := ""
+		if *existing != nil {
+			current = **existing
 		}
-		updatedProject := &modelv2.Project{
-			Name:       harborConfiguration.Spec.ProjectReq.ProjectName,
-			RegistryID: srcRegistry.ID,
-			ProjectID:  existingProject.ProjectID,
+		if current == value || current == "" && value == "false" {
+			return
 		}
-		// Note: Only positive values of storageQuota are supported through this method.
-		// Use the 'UpdateStorageQuotaByProjectID' method when `project.StorageQuota`is `-1`
-		unlimitedStorage := int64(-1)
-		if requestedProject.StorageLimit != &unlimitedStorage {
-			err = client.UpdateProject(ctx, updatedProject, requestedProject.StorageLimit)
-			if err != nil {
-				return ctrl.Result{}, err
-			}
-		} else {
-			err = client.UpdateStorageQuotaByProjectID(ctx, int64(updatedProject.ProjectID), unlimitedStorage)
-			if err != nil {
-				return ctrl.Result{}, err
-			}
+		drifted = append(drifted, fieldChange(field, current, value))
+		*existing = &value
+	}
+	applyBool := func(field string, existing **string, value *bool) {
+		if value != nil {
+			apply(field, existing, strconv.FormatBool(*value))
 		}
-	} else {
-		return ctrl.Result{}, err
 	}
-	return ctrl.Result{}, nil
+
+	applyBool("autoScan", &metadata.AutoScan, requested.AutoScan)
+	applyBool("enableContentTrust", &metadata.EnableContentTrust, requested.EnableContentTrust)
+	applyBool("enableContentTrustCosign", &metadata.EnableContentTrustCosign, requested.EnableContentTrustCosign)
+	applyBool("preventVul", &metadata.PreventVul, requested.PreventVul)
+	applyBool("reuseSysCVEAllowlist", &metadata.ReuseSysCVEAllowlist, requested.ReuseSysCVEAllowlist)
+	if requested.Severity != "" {
+		apply("severity", &metadata.Severity, requested.Severity)
+	}
+	return drifted
 }
 
-func (r *HarborConfigurationReconciler) replicationRuleReconciliation(ctx context.Context, harborConfiguration harborconfigurationv1alpha1.HarborConfiguration, registry modelv2.Registry, client *apiv2.RESTClient) (ctrl.Result, error) {
-	srcRegistry, err := client.GetRegistryByName(ctx, harborConfiguration.Spec.Replication.RegistryName)
+// replicationRuleReconciliation creates the replication rule or brings it back
+// in line with the spec, returning the fields that had drifted in Harbor.
+func replicationRuleReconciliation(ctx context.Context, replication harborconfigurationv1alpha1.Replication, owner ownership, client *harborClient) ([]string, error) {
+	srcRegistry, err := client.GetRegistryByName(ctx, replication.RegistryName)
 	if err != nil {
-		return ctrl.Result{}, err
+		return nil, err
 	}
 
-	reqFilters := make([]*modelv2.ReplicationFilter, 0)
-	for _, v := range harborConfiguration.Spec.Replication.Filters {
-		temp := modelv2.ReplicationFilter{}
-		err := json.Unmarshal(v.Raw, &temp)
+	reqFilters := replicationFilters(replication.Filters)
+
+	var reqDestinationRegistry *modelv2.Registry
+	if replication.DestinationRegistry != nil {
+		err = json.Unmarshal(replication.DestinationRegistry.Raw, &reqDestinationRegistry)
 		if err != nil {
-			return ctrl.Result{}, err
+			return nil, err
 		}
-		reqFilters = append(reqFilters, &temp)
 	}
 
-	var reqDestinationRegistry *modelv2.Registry
-	if harborConfiguration.Spec.Replication.DestinationRegistry != nil {
-		err = json.Unmarshal(harborConfiguration.Spec.Replication.DestinationRegistry.Raw, reqDestinationRegistry)
-		if err != nil {
-			return ctrl.Result{}, err
+	reqTrigger, err := replicationTrigger(replication.TriggerMode)
+	if err != nil {
+		return nil, err
+	}
+
+	replicationFound, err := client.GetReplicationPolicyByName(ctx, replication.Name)
+	if errors.Is(err, &harborerrors.ErrNotFound{}) {
+		return []string{driftDeleted}, client.NewReplicationPolicy(ctx,
+			reqDestinationRegistry,
+			srcRegistry,
+			replication.ReplicateDeletion,
+			replication.Override,
+			replication.EnablePolicy,
+			reqFilters,
+			reqTrigger,
+			replication.DestinationNamespace,
+			withOwner(replication.Description, owner.Owner),
+			replication.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	currentOwner := describedOwner(replicationFound.Description)
+	if err := owner.claim("replication rule", replication.Name, replicationFound.ID, currentOwner); err != nil {
+		return nil, err
+	}
+
+	update := modelv2.ReplicationPolicy{
+		Name:              replication.Name,
+		Description:       withOwner(replication.Description, owner.Owner),
+		SrcRegistry:       srcRegistry,
+		DestNamespace:     replication.DestinationNamespace,
+		DestRegistry:      reqDestinationRegistry,
+		Filters:           reqFilters,
+		Trigger:           reqTrigger,
+		Override:          replication.Override,
+		Enabled:           replication.EnablePolicy,
+		ReplicateDeletion: replication.ReplicateDeletion,
+	}
+	drifted := replicationPolicyDrift(replicationFound, &update)
+	if len(drifted) == 0 && currentOwner == owner.Owner {
+		return nil, nil
+	}
+
+	err = client.UpdateReplicationPolicy(ctx, &update, replicationFound.ID)
+	if err != nil {
+		return nil, err
+	}
+	return drifted, nil
+}
+
+// replicationFilters converts the typed filters of the spec into Harbor filters.
+func replicationFilters(filters []harborconfigurationv1alpha1.ReplicationFilter) []*modelv2.ReplicationFilter {
+	reqFilters := make([]*modelv2.ReplicationFilter, 0, len(filters))
+	for _, filter := range filters {
+		var value interface{} = string(filter.Value)
+		if filter.Type == "label" {
+			labels := strings.Split(string(filter.Value), ",")
+			for i := range labels {
+				labels[i] = strings.TrimSpace(labels[i])
+			}
+			value = labels
 		}
+		reqFilters = append(reqFilters, &modelv2.ReplicationFilter{
+			Type:       filter.Type,
+			Value:      value,
+			Decoration: filter.Decoration,
+		})
+	}
+	return reqFilters
+}
 
+// reconcileAll reconciles every declared registry, then every project and
+// finally every replication rule, so that the registries a project or rule
+// refers to exist first. A failing item is recorded in its status entry and
+// does not stop the remaining items from being reconciled.
+func (r *HarborConfigurationReconciler) reconcileAll(ctx context.Context, harborConfiguration *harborconfigurationv1alpha1.HarborConfiguration, legacy bool, client *harborClient) (ctrl.Result, error) {
+	errorChain := chain.New()
+
+	// Differences found in Harbor are only drift when the spec has not changed
+	// since the item was last reconciled successfully.
+	var drift []string
+	unchanged := harborConfiguration.Status.ObservedGeneration == harborConfiguration.Generation
+	reconciled := make(map[string]bool)
+	for _, registryStatus := range harborConfiguration.Status.Registries {
+		reconciled["registry/"+registryStatus.Name] = unchanged && registryStatus.Ready
+	}
+	for _, projectStatus := range harborConfiguration.Status.Projects {
+		reconciled["project/"+projectStatus.Name] = unchanged && projectStatus.Ready
+	}
+	for _, replicationStatus := range harborConfiguration.Status.Replications {
+		reconciled["replication/"+replicationStatus.Name] = unchanged && replicationStatus.Ready
 	}
+	ids := knownIDs(harborConfiguration)
 
-	var reqTrigger *modelv2.ReplicationTrigger
-	if harborConfiguration.Spec.Replication.TriggerMode != nil {
-		err = json.Unmarshal(harborConfiguration.Spec.Replication.TriggerMode.Raw, &reqTrigger)
+	registryErrors := chain.New()
+	registryStatuses := make([]harborconfigurationv1alpha1.RegistryStatus, 0)
+	for _, registry := range harborConfiguration.Spec.AllRegistries() {
+		registryStatus := harborconfigurationv1alpha1.RegistryStatus{Name: registry.Name}
+		id, drifted, err := reconcileRegistry(ctx, r.ClientSet, harborConfiguration.Namespace, registry, configurationOwnership(r.Recorder, harborConfiguration, ids["registry/"+registry.Name], legacy), client)
+		if len(drifted) > 0 && reconciled["registry/"+registry.Name] {
+			drift = append(drift, driftMessage("registry", registry.Name, drifted))
+		}
 		if err != nil {
-			return ctrl.Result{}, err
+			registryStatus.Message = err.Error()
+			registryErrors.Add(fmt.Errorf("registry %q: %w", registry.Name, err))
+			errorChain.Add(fmt.Errorf("registry %q: %w", registry.Name, err))
+		} else {
+			registryStatus.ID = id
+			registryStatus.Ready = true
 		}
+		registryStatuses = append(registryStatuses, registryStatus)
+	}
+	harborConfiguration.Status.Registries = registryStatuses
+	harborConfiguration.Status.RegistryId = 0
+	if harborConfiguration.Spec.Registry != nil {
+		harborConfiguration.Status.RegistryId = registryStatuses[0].ID
 	}
+	setItemsCondition(harborConfiguration, harborconfigurationv1alpha1.ConditionRegistryReady, registryErrors)
 
-	err = client.NewReplicationPolicy(ctx,
-		reqDestinationRegistry,
-		srcRegistry,
-		harborConfiguration.Spec.Replication.ReplicateDeletion,
-		harborConfiguration.Spec.Replication.Override,
-		harborConfiguration.Spec.Replication.EnablePolicy,
-		reqFilters,
-		reqTrigger,
-		harborConfiguration.Spec.Replication.DestinationNamespace,
-		harborConfiguration.Spec.Replication.Description,
-		harborConfiguration.Spec.Replication.Name)
-
-	if errors.Is(err, &rep.ErrReplicationNameAlreadyExists{}) {
-		update := modelv2.ReplicationPolicy{
-			Name:          harborConfiguration.Spec.Replication.Name,
-			Description:   harborConfiguration.Spec.Replication.Description,
-			SrcRegistry:   srcRegistry,
-			DestNamespace: harborConfiguration.Spec.Replication.DestinationNamespace,
-			DestRegistry:  reqDestinationRegistry,
-			Filters:       reqFilters,
-			Trigger:       reqTrigger,
-			Override:      harborConfiguration.Spec.Replication.Override,
-			Enabled:       harborConfiguration.Spec.Replication.EnablePolicy,
-		}
-
-		replicationFound, err := client.GetReplicationPolicyByName(ctx, harborConfiguration.Spec.Replication.Name)
+	projectErrors := chain.New()
+	projectStatuses := make([]harborconfigurationv1alpha1.ProjectStatus, 0)
+	for _, project := range harborConfiguration.Spec.AllProjects() {
+		projectStatus := harborconfigurationv1alpha1.ProjectStatus{Name: project.ProjectName}
+		id, drifted, err := reconcileProject(ctx, project, configurationOwnership(r.Recorder, harborConfiguration, ids["project/"+project.ProjectName], legacy), client)
+		if len(drifted) > 0 && reconciled["project/"+project.ProjectName] {
+			drift = append(drift, driftMessage("project", project.ProjectName, drifted))
+		}
 		if err != nil {
-			return ctrl.Result{}, err
+			projectStatus.Message = err.Error()
+			projectErrors.Add(fmt.Errorf("project %q: %w", project.ProjectName, err))
+			errorChain.Add(fmt.Errorf("project %q: %w", project.ProjectName, err))
+		} else {
+			projectStatus.ID = id
+			projectStatus.Ready = true
+		}
+		projectStatuses = append(projectStatuses, projectStatus)
+	}
+	harborConfiguration.Status.Projects = projectStatuses
+	harborConfiguration.Status.ProjectId = ""
+	if harborConfiguration.Spec.ProjectReq != nil {
+		harborConfiguration.Status.ProjectId = projectStatuses[0].ID
+	}
+	setItemsCondition(harborConfiguration, harborconfigurationv1alpha1.ConditionProjectReady, projectErrors)
+
+	previousRunStatuses := make(map[string]harborconfigurationv1alpha1.ReplicationRunStatus)
+	for _, replicationStatus := range harborConfiguration.Status.Replications {
+		previousRunStatuses[replicationStatus.Name] = replicationStatus.ReplicationRunStatus
+	}
+
+	replicationErrors := chain.New()
+	replicationStatuses := make([]harborconfigurationv1alpha1.ReplicationStatus, 0)
+	for _, replication := range harborConfiguration.Spec.AllReplications() {
+		replicationStatus := harborconfigurationv1alpha1.ReplicationStatus{
+			Name:                 replication.Name,
+			ReplicationRunStatus: previousRunStatuses[replication.Name],
+		}
+		owner := configurationOwnership(r.Recorder, harborConfiguration, ids["replication/"+replication.Name], legacy)
+		id, drifted, err := reconcileReplication(ctx, replication, owner, client)
+		if len(drifted) > 0 && reconciled["replication/"+replication.Name] {
+			drift = append(drift, driftMessage("replication", replication.Name, drifted))
+		}
+		if err == nil {
+			err = runReplicationIfRequested(ctx, replication, id, &replicationStatus.ReplicationRunStatus, owner.Events, client)
+			if err != nil {
+				err = fmt.Errorf("triggering execution: %w", err)
+			}
 		}
-		// If ErrReplicationNameAlreadyExists is returned, it is because there is no new
-		// information to update the object with.
-		err = client.UpdateReplicationPolicy(ctx, &update, replicationFound.ID)
-		if errors.Is(err, &rep.ErrReplicationNameAlreadyExists{}) {
-			err = fmt.Errorf("no update required: %w", err)
+		if err == nil {
+			err = refreshReplicationExecution(ctx, replication.Name, &replicationStatus.ReplicationRunStatus, client)
 		}
 		if err != nil {
-			return ctrl.Result{}, err
+			replicationStatus.Message = err.Error()
+			replicationErrors.Add(fmt.Errorf("replication %q: %w", replication.Name, err))
+			errorChain.Add(fmt.Errorf("replication %q: %w", replication.Name, err))
+		} else {
+			replicationStatus.ID = id
+			replicationStatus.Ready = true
+			replicationStatus.NextScheduledTime = nextScheduledTime(replication.TriggerMode, time.Now())
 		}
-	} else {
-		return ctrl.Result{}, err
+		replicationStatuses = append(replicationStatuses, replicationStatus)
 	}
-	return ctrl.Result{}, nil
+	harborConfiguration.Status.Replications = replicationStatuses
+	harborConfiguration.Status.ReplicationId = 0
+	if harborConfiguration.Spec.Replication != nil {
+		harborConfiguration.Status.ReplicationId = replicationStatuses[0].ID
+	}
+	setItemsCondition(harborConfiguration, harborconfigurationv1alpha1.ConditionReplicationReady, replicationErrors)
+
+	reportDrift(r.Recorder, harborConfiguration, &harborConfiguration.Status.Conditions, drift)
+	reportConflicts(harborConfiguration, &harborConfiguration.Status.Conditions, errorChain.Errors())
+
+	runStatuses := make(map[string]harborconfigurationv1alpha1.ReplicationRunStatus)
+	for _, replicationStatus := range replicationStatuses {
+		runStatuses[replicationStatus.Name] = replicationStatus.ReplicationRunStatus
+	}
+	result := ctrl.Result{RequeueAfter: setReplicationSucceededCondition(&harborConfiguration.Status.Conditions, harborConfiguration.Generation, runStatuses)}
+
+	if len(errorChain.Errors()) > 0 {
+		setCondition(harborConfiguration, harborconfigurationv1alpha1.ConditionReady, v1.ConditionFalse, harborconfigurationv1alpha1.ReasonReconcileFailed, errorChain.Error())
+		return result, errorChain
+	}
+	return result, nil
 }
 
-func (r *HarborConfigurationReconciler) reconcileAll(ctx context.Context, harborConfiguration harborconfigurationv1alpha1.HarborConfiguration, client *apiv2.RESTClient) (ctrl.Result, error) {
-	registry := &modelv2.Registry{
-		Name:        harborConfiguration.Spec.Registry.Name,
-		Type:        harborConfiguration.Spec.Registry.Provider,
-		URL:         harborConfiguration.Spec.Registry.EndpointUrl,
-		Description: harborConfiguration.Spec.Registry.Description,
-		Credential:  (*modelv2.RegistryCredential)(harborConfiguration.Spec.Registry.Credential),
+// configurationOwnership returns the ownership of a Harbor object declared by
+// the HarborConfiguration.
+func configurationOwnership(recorder record.EventRecorder, harborConfiguration *harborconfigurationv1alpha1.HarborConfiguration, knownID int64, legacy bool) ownership {
+	owner := newOwnership("HarborConfiguration", harborConfiguration, harborConfiguration.Spec.AdoptionPolicy, knownID, recorder)
+	owner.Legacy = legacy
+	return owner
+}
+
+// adoptsLegacyObjects reports whether the unmarked Harbor objects declared by
+// the HarborConfiguration are treated as its own: it predates ownership
+// markers and not every section has been reconciled since.
+func adoptsLegacyObjects(harborConfiguration *harborconfigurationv1alpha1.HarborConfiguration) bool {
+	_, adopting := harborConfiguration.Annotations[legacyAdoptionAnnotation]
+	return adopting || predatesOwnershipMarkers(harborConfiguration)
+}
+
+// markLegacyAdoption records that the HarborConfiguration adopts the unmarked
+// Harbor objects it declares.
+func markLegacyAdoption(ctx context.Context, c client.Writer, harborConfiguration *harborconfigurationv1alpha1.HarborConfiguration) error {
+	if _, adopting := harborConfiguration.Annotations[legacyAdoptionAnnotation]; adopting {
+		return nil
+	}
+	v1.SetMetaDataAnnotation(&harborConfiguration.ObjectMeta, legacyAdoptionAnnotation, "true")
+	return c.Update(ctx, harborConfiguration)
+}
+
+// finishLegacyAdoption clears the legacy adoption once every section of the
+// HarborConfiguration has been reconciled, which marked the objects adopted.
+func finishLegacyAdoption(ctx context.Context, c client.Writer, harborConfiguration *harborconfigurationv1alpha1.HarborConfiguration) error {
+	if _, adopting := harborConfiguration.Annotations[legacyAdoptionAnnotation]; !adopting {
+		return nil
 	}
+	delete(harborConfiguration.Annotations, legacyAdoptionAnnotation)
+	return c.Update(ctx, harborConfiguration)
+}
 
-	_, err := r.registryReconciliation(ctx, harborConfiguration, *registry, client)
+// predatesOwnershipMarkers reports whether the HarborConfiguration was
+// reconciled by an operator that neither marked the Harbor objects nor wrote
+// the status: it carries the finalizer, but no status was ever recorded.
+func predatesOwnershipMarkers(harborConfiguration *harborconfigurationv1alpha1.HarborConfiguration) bool {
+	return controllerutil.ContainsFinalizer(harborConfiguration, harborFinaliserName) &&
+		harborConfiguration.Status.ObservedGeneration == 0 &&
+		len(knownIDs(harborConfiguration)) == 0
+}
+
+// knownIDs returns the Harbor IDs recorded in the status, keyed by kind and
+// name of the object.
+func knownIDs(harborConfiguration *harborconfigurationv1alpha1.HarborConfiguration) map[string]int64 {
+	ids := make(map[string]int64)
+	// Objects reconciled before multiple registries, projects and replication
+	// rules were supported are only recorded in the legacy IDs.
+	if harborConfiguration.Spec.Registry != nil && harborConfiguration.Status.RegistryId != 0 {
+		ids["registry/"+harborConfiguration.Spec.Registry.Name] = harborConfiguration.Status.RegistryId
+	}
+	if harborConfiguration.Spec.ProjectReq != nil {
+		if id, err := strconv.ParseInt(harborConfiguration.Status.ProjectId, 10, 64); err == nil {
+			ids["project/"+harborConfiguration.Spec.ProjectReq.ProjectName] = id
+		}
+	}
+	if harborConfiguration.Spec.Replication != nil && harborConfiguration.Status.ReplicationId != 0 {
+		ids["replication/"+harborConfiguration.Spec.Replication.Name] = harborConfiguration.Status.ReplicationId
+	}
+
+	for _, registryStatus := range harborConfiguration.Status.Registries {
+		if registryStatus.ID != 0 {
+			ids["registry/"+registryStatus.Name] = registryStatus.ID
+		}
+	}
+	for _, projectStatus := range harborConfiguration.Status.Projects {
+		if id, err := strconv.ParseInt(projectStatus.ID, 10, 64); err == nil {
+			ids["project/"+projectStatus.Name] = id
+		}
+	}
+	for _, replicationStatus := range harborConfiguration.Status.Replications {
+		if replicationStatus.ID != 0 {
+			ids["replication/"+replicationStatus.Name] = replicationStatus.ID
+		}
+	}
+	return ids
+}
+
+// reconcileRegistry creates or updates a single registry and returns its
+// Harbor ID and the fields that had drifted from the spec.
+func reconcileRegistry(ctx context.Context, clientSet *kubernetes.Clientset, namespace string, registry harborconfigurationv1alpha1.Registry, owner ownership, client *harborClient) (int64, []string, error) {
+	credential, err := resolveRegistryCredential(ctx, clientSet, namespace, registry)
 	if err != nil {
-		return ctrl.Result{}, err
+		owner.Events.failed("registry", registry.Name, err)
+		return 0, nil, err
 	}
 
-	_, err = r.projectReconciliation(ctx, harborConfiguration, *registry, client)
+	drifted, err := registryReconciliation(ctx, modelv2.Registry{
+		Name:        registry.Name,
+		Type:        registry.Provider,
+		URL:         registry.EndpointUrl,
+		Description: registry.Description,
+		Credential:  credential,
+	}, owner, client)
 	if err != nil {
-		return ctrl.Result{}, err
+		owner.Events.failed("registry", registry.Name, err)
+		return 0, nil, err
 	}
 
-	_, err = r.replicationRuleReconciliation(ctx, harborConfiguration, *registry, client)
+	srcRegistry, err := client.GetRegistryByName(ctx, registry.Name)
 	if err != nil {
-		return ctrl.Result{}, err
+		owner.Events.failed("registry", registry.Name, err)
+		return 0, nil, err
 	}
-	return ctrl.Result{}, err
+	owner.Events.reconciled("registry", registry.Name, srcRegistry.ID, drifted)
+	return srcRegistry.ID, drifted, nil
 }
 
-func getHarborSecret(ctx context.Context, clientSet *kubernetes.Clientset, harborcluster *harborOperator.HarborCluster) (string, error) {
-	passwordSecret, err := clientSet.CoreV1().Secrets(harborcluster.Namespace).Get(ctx, harborcluster.Spec.HarborAdminPasswordRef, v1.GetOptions{})
+// reconcileProject creates or updates a single project and returns its Harbor
+// ID and the fields that had drifted from the spec.
+func reconcileProject(ctx context.Context, project harborconfigurationv1alpha1.ProjectReq, owner ownership, client *harborClient) (string, []string, error) {
+	drifted, err := projectReconciliation(ctx, project, owner, client)
 	if err != nil {
-		return "", err
+		owner.Events.failed("project", project.ProjectName, err)
+		return "", nil, err
 	}
-	passwords := passwordSecret.Data
-	for key, value := range passwords {
-		if key == "secret" && string(value) != "" {
-			return string(value), nil
-		}
+
+	existingProject, err := client.GetProject(ctx, project.ProjectName)
+	if err != nil {
+		owner.Events.failed("project", project.ProjectName, err)
+		return "", nil, err
 	}
-	return "", errors.New("no key \"secret\" found")
+	owner.Events.reconciled("project", project.ProjectName, int64(existingProject.ProjectID), drifted)
+	return strconv.Itoa(int(existingProject.ProjectID)), drifted, nil
 }
 
-func getHarborURL(harborcluster *harborOperator.HarborCluster) string {
-	url := os.Getenv("HARBOR_CORE_URL")
-	if url == "" {
-		url = fmt.Sprintf("http://%s-harbor-harbor-core.%s/api/v2.0", harborcluster.Name, harborcluster.Namespace)
+// reconcileReplication creates or updates a single replication rule and
+// returns its Harbor ID and the fields that had drifted from the spec.
+func reconcileReplication(ctx context.Context, replication harborconfigurationv1alpha1.Replication, owner ownership, client *harborClient) (int64, []string, error) {
+	drifted, err := replicationRuleReconciliation(ctx, replication, owner, client)
+	if err != nil {
+		owner.Events.failed("replication rule", replication.Name, err)
+		return 0, nil, err
 	}
-	return url
-}
 
-func getConcreteHarborType(ctx context.Context, crdClient dynamic.ResourceInterface, harborConfiguration harborconfigurationv1alpha1.HarborConfiguration, harborTarget harborOperator.HarborCluster) (harborOperator.HarborCluster, error) {
-	harborUnstructured, err := crdClient.Get(ctx, harborConfiguration.Spec.HarborTarget.Name, v1.GetOptions{
-		TypeMeta: v1.TypeMeta{
-			Kind:       "HarborCluster",
-			APIVersion: "v1alpha3",
-		},
-	})
+	replicationFound, err := client.GetReplicationPolicyByName(ctx, replication.Name)
 	if err != nil {
-		return harborTarget, err
+		owner.Events.failed("replication rule", replication.Name, err)
+		return 0, nil, err
 	}
+	owner.Events.reconciled("replication rule", replication.Name, replicationFound.ID, drifted)
+	return replicationFound.ID, drifted, nil
+}
 
-	err = runtime.DefaultUnstructuredConverter.FromUnstructured(harborUnstructured.UnstructuredContent(), &harborTarget)
+// resolveRegistryCredential builds the Harbor registry credential from the spec,
+// reading the access key and secret from the referenced Secret when one is set.
+func resolveRegistryCredential(ctx context.Context, clientSet *kubernetes.Clientset, namespace string, registry harborconfigurationv1alpha1.Registry) (*modelv2.RegistryCredential, error) {
+	specCredential := registry.Credential
+	if specCredential == nil {
+		return nil, nil
+	}
+
+	credential := &modelv2.RegistryCredential{
+		AccessKey:    specCredential.AccessKey,
+		AccessSecret: specCredential.AccessSecret,
+		Type:         specCredential.Type,
+	}
+	if specCredential.SecretRef == nil {
+		return credential, nil
+	}
+
+	secretRef := specCredential.SecretRef
+	credentialSecret, err := clientSet.CoreV1().Secrets(namespace).Get(ctx, secretRef.Name, v1.GetOptions{})
 	if err != nil {
-		return harborTarget, err
+		return nil, err
 	}
-	return harborTarget, err
+
+	accessKeyKey := secretRef.AccessKeyKey
+	if accessKeyKey == "" {
+		accessKeyKey = "access_key"
+	}
+	accessSecretKey := secretRef.AccessSecretKey
+	if accessSecretKey == "" {
+		accessSecretKey = "access_secret"
+	}
+
+	accessKey, ok := credentialSecret.Data[accessKeyKey]
+	if !ok {
+		return nil, fmt.Errorf("no key %q found in secret %s/%s", accessKeyKey, credentialSecret.Namespace, credentialSecret.Name)
+	}
+	accessSecret, ok := credentialSecret.Data[accessSecretKey]
+	if !ok {
+		return nil, fmt.Errorf("no key %q found in secret %s/%s", accessSecretKey, credentialSecret.Namespace, credentialSecret.Name)
+	}
+
+	credential.AccessKey = string(accessKey)
+	credential.AccessSecret = string(accessSecret)
+	return credential, nil
 }
 
-func deleteAll(ctx context.Context, harborConfiguration harborconfigurationv1alpha1.HarborConfiguration, client *apiv2.RESTClient) (ctrl.Result, error) {
+// deleteAll deletes the Harbor objects of the HarborConfiguration, leaving
+// those with an Orphan deletion policy in Harbor without their ownership
+// marker. Registries still used by an orphaned project or replication rule are
+// orphaned too, as Harbor refuses to delete them. Failing to remove a marker
+// does not hold up the deletion.
+func deleteAll(ctx context.Context, recorder record.EventRecorder, harborConfiguration harborconfigurationv1alpha1.HarborConfiguration, client *harborClient) (ctrl.Result, error) {
+	deletionPolicy := harborConfiguration.Spec.DeletionPolicy.Or(harborconfigurationv1alpha1.DeletionPolicyDelete)
+	ids := knownIDs(&harborConfiguration)
+	legacy := adoptsLegacyObjects(&harborConfiguration)
+	orphanedRegistryUsers := make(map[string]bool)
+
 	errorChain := chain.New()
-	_, deleteReplicationRuleErr := deleteReplicationRule(ctx, harborConfiguration, client)
-	if deleteReplicationRuleErr != nil && !(errors.Is(deleteReplicationRuleErr, &harborerrors.ErrNotFound{})) {
-		errorChain.Add(deleteReplicationRuleErr)
+	for _, replication := range harborConfiguration.Spec.AllReplications() {
+		owner := configurationOwnership(recorder, &harborConfiguration, ids["replication/"+replication.Name], legacy)
+		if replication.DeletionPolicy.Or(deletionPolicy) == harborconfigurationv1alpha1.DeletionPolicyOrphan {
+			orphanedRegistryUsers[replication.RegistryName] = true
+			err := releaseReplicationRule(ctx, replication, owner, client)
+			if err != nil && !errors.Is(err, &harborerrors.ErrNotFound{}) {
+				owner.Events.releaseFailed("replication rule", replication.Name, err)
+			}
+			continue
+		}
+		_, deleteReplicationRuleErr := deleteReplicationRule(ctx, replication, owner, client)
+		if deleteReplicationRuleErr != nil && !(errors.Is(deleteReplicationRuleErr, &harborerrors.ErrNotFound{})) {
+			errorChain.Add(deleteReplicationRuleErr)
+		}
 	}
-	_, deleteProjectErr := deleteProject(ctx, harborConfiguration, client)
-	if deleteProjectErr != nil && !(errors.Is(deleteProjectErr, &harborerrors.ErrProjectNotFound{})) {
-		errorChain.Add(deleteProjectErr)
+	for _, project := range harborConfiguration.Spec.AllProjects() {
+		owner := configurationOwnership(recorder, &harborConfiguration, ids["project/"+project.ProjectName], legacy)
+		if project.DeletionPolicy.Or(deletionPolicy) == harborconfigurationv1alpha1.DeletionPolicyOrphan {
+			if project.ProxyCacheRegistryName != "" {
+				orphanedRegistryUsers[project.ProxyCacheRegistryName] = true
+			}
+			err := releaseProject(ctx, project, owner, client)
+			if err != nil && !errors.Is(err, &harborerrors.ErrProjectNotFound{}) {
+				owner.Events.releaseFailed("project", project.ProjectName, err)
+			}
+			continue
+		}
+		_, deleteProjectErr := deleteProject(ctx, project, owner, client)
+		if deleteProjectErr != nil && !(errors.Is(deleteProjectErr, &harborerrors.ErrProjectNotFound{})) {
+			errorChain.Add(deleteProjectErr)
+		}
 	}
-	_, deleteRegistryErr := deleteRegistry(ctx, harborConfiguration, client)
-	if deleteRegistryErr != nil && !(errors.Is(deleteRegistryErr, &harborerrors.ErrRegistryNotFound{})) {
-		errorChain.Add(deleteRegistryErr)
+	for _, registry := range harborConfiguration.Spec.AllRegistries() {
+		owner := configurationOwnership(recorder, &harborConfiguration, ids["registry/"+registry.Name], legacy)
+		if registry.DeletionPolicy.Or(deletionPolicy) == harborconfigurationv1alpha1.DeletionPolicyOrphan || orphanedRegistryUsers[registry.Name] {
+			err := releaseRegistry(ctx, registry, owner, client)
+			if err != nil && !errors.Is(err, &harborerrors.ErrRegistryNotFound{}) {
+				owner.Events.releaseFailed("registry", registry.Name, err)
+			}
+			continue
+		}
+		_, deleteRegistryErr := deleteRegistry(ctx, registry, owner, client)
+		if deleteRegistryErr != nil && !(errors.Is(deleteRegistryErr, &harborerrors.ErrRegistryNotFound{})) {
+			errorChain.Add(deleteRegistryErr)
+		}
 	}
 
 	if len(errorChain.Errors()) > 0 {
-		return ctrl.Result{}, errorChain.Unwrap()
+		return ctrl.Result{}, errorChain
 	}
 
 	return ctrl.Result{}, nil
 }
 
-func deleteReplicationRule(ctx context.Context, harborConfiguration harborconfigurationv1alpha1.HarborConfiguration, client *apiv2.RESTClient) (ctrl.Result, error) {
-	replicationFound, err := client.GetReplicationPolicyByName(ctx, harborConfiguration.Spec.Replication.Name)
+// orphansAll reports whether the deletion of the HarborConfiguration leaves
+// all of its Harbor objects in Harbor.
+func orphansAll(harborConfiguration *harborconfigurationv1alpha1.HarborConfiguration) bool {
+	deletionPolicy := harborConfiguration.Spec.DeletionPolicy.Or(harborconfigurationv1alpha1.DeletionPolicyDelete)
+	for _, replication := range harborConfiguration.Spec.AllReplications() {
+		if replication.DeletionPolicy.Or(deletionPolicy) != harborconfigurationv1alpha1.DeletionPolicyOrphan {
+			return false
+		}
+	}
+	for _, project := range harborConfiguration.Spec.AllProjects() {
+		if project.DeletionPolicy.Or(deletionPolicy) != harborconfigurationv1alpha1.DeletionPolicyOrphan {
+			return false
+		}
+	}
+	for _, registry := range harborConfiguration.Spec.AllRegistries() {
+		if registry.DeletionPolicy.Or(deletionPolicy) != harborconfigurationv1alpha1.DeletionPolicyOrphan {
+			return false
+		}
+	}
+	return true
+}
+
+// deleteReplicationRule deletes the replication rule unless it is owned by
+// another resource or by no resource at all.
+func deleteReplicationRule(ctx context.Context, replication harborconfigurationv1alpha1.Replication, owner ownership, client *harborClient) (ctrl.Result, error) {
+	replicationFound, err := client.GetReplicationPolicyByName(ctx, replication.Name)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
+	if !owner.owns(replicationFound.ID, describedOwner(replicationFound.Description)) {
+		return ctrl.Result{}, nil
+	}
 	err = client.DeleteReplicationPolicyByID(ctx, replicationFound.ID)
 	if err != nil {
+		owner.Events.deleteFailed("replication rule", replication.Name, replicationFound.ID, err)
 		return ctrl.Result{}, err
 	}
+	owner.Events.deleted("replication rule", replication.Name, replicationFound.ID)
 
 	return ctrl.Result{}, nil
 }
 
-func deleteProject(ctx context.Context, harborConfiguration harborconfigurationv1alpha1.HarborConfiguration, client *apiv2.RESTClient) (ctrl.Result, error) {
-	requestedProject := &modelv2.ProjectReq{
-		ProjectName:  harborConfiguration.Spec.ProjectReq.ProjectName,
-		Public:       harborConfiguration.Spec.ProjectReq.Public,
-		StorageLimit: harborConfiguration.Spec.ProjectReq.StorageQuota,
+// deleteProject deletes the project unless it is owned by another resource
+// or by no resource at all.
+func deleteProject(ctx context.Context, project harborconfigurationv1alpha1.ProjectReq, owner ownership, client *harborClient) (ctrl.Result, error) {
+	existingProject, err := client.GetProject(ctx, project.ProjectName)
+	if err != nil {
+		return ctrl.Result{}, err
 	}
-
-	existingProject, err := client.GetProject(ctx, requestedProject.ProjectName)
+	currentOwner, err := projectOwner(ctx, existingProject.ProjectID, client)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
+	if !owner.owns(int64(existingProject.ProjectID), currentOwner) {
+		return ctrl.Result{}, nil
+	}
 
 	err = client.DeleteProject(ctx, existingProject.Name)
 	if err != nil {
+		owner.Events.deleteFailed("project", existingProject.Name, int64(existingProject.ProjectID), err)
 		return ctrl.Result{}, err
 	}
+	owner.Events.deleted("project", existingProject.Name, int64(existingProject.ProjectID))
 
 	return ctrl.Result{}, nil
 }
 
-func deleteRegistry(ctx context.Context, harborConfiguration harborconfigurationv1alpha1.HarborConfiguration, client *apiv2.RESTClient) (ctrl.Result, error) {
-	srcRegistry, err := client.GetRegistryByName(ctx, harborConfiguration.Spec.Registry.Name)
-	if errors.Is(err, &harborerrors.ErrRegistryNotFound{}) {
+// deleteRegistry deletes the registry unless it is owned by another resource
+// or by no resource at all.
+func deleteRegistry(ctx context.Context, registry harborconfigurationv1alpha1.Registry, owner ownership, client *harborClient) (ctrl.Result, error) {
+	srcRegistry, err := client.GetRegistryByName(ctx, registry.Name)
+	if err != nil {
 		return ctrl.Result{}, err
 	}
+	if !owner.owns(srcRegistry.ID, describedOwner(srcRegistry.Description)) {
+		return ctrl.Result{}, nil
+	}
 
 	err = client.DeleteRegistryByID(ctx, srcRegistry.ID)
 	if err != nil {
+		owner.Events.deleteFailed("registry", registry.Name, srcRegistry.ID, err)
 		return ctrl.Result{}, err
 	}
+	owner.Events.deleted("registry", registry.Name, srcRegistry.ID)
 	return ctrl.Result{}, nil
 }
 
-func triggerReplication(ctx context.Context, harborConfiguration harborconfigurationv1alpha1.HarborConfiguration, client *apiv2.RESTClient) (ctrl.Result, error) {
-	replicationFound, err := client.GetReplicationPolicyByName(ctx, harborConfiguration.Spec.Replication.Name)
+// releaseReplicationRule removes the ownership marker of a replication rule
+// the resource owns, leaving the rule in Harbor.
+func releaseReplicationRule(ctx context.Context, replication harborconfigurationv1alpha1.Replication, owner ownership, client *harborClient) error {
+	replicationFound, err := client.GetReplicationPolicyByName(ctx, replication.Name)
 	if err != nil {
-		return ctrl.Result{}, err
+		return err
+	}
+	currentOwner := describedOwner(replicationFound.Description)
+	if !owner.owns(replicationFound.ID, currentOwner) {
+		return nil
 	}
+	if currentOwner != "" {
+		replicationFound.Description = stripOwner(replicationFound.Description)
+		if err := client.UpdateReplicationPolicy(ctx, replicationFound, replicationFound.ID); err != nil {
+			return err
+		}
+	}
+	owner.Events.orphaned("replication rule", replication.Name, replicationFound.ID)
+	return nil
+}
 
-	trigger := &modelv2.StartReplicationExecution{
-		PolicyID: replicationFound.ID,
+// releaseProject removes the ownership label of a project the resource owns,
+// leaving the project in Harbor.
+func releaseProject(ctx context.Context, project harborconfigurationv1alpha1.ProjectReq, owner ownership, client *harborClient) error {
+	existingProject, err := client.GetProject(ctx, project.ProjectName)
+	if err != nil {
+		return err
 	}
-	err = client.TriggerReplicationExecution(ctx, trigger)
+	label, err := projectOwnerLabel(ctx, existingProject.ProjectID, client)
 	if err != nil {
-		return ctrl.Result{}, err
+		return err
+	}
+	var currentOwner string
+	if label != nil {
+		currentOwner = strings.TrimSpace(label.Description)
+	}
+	if !owner.owns(int64(existingProject.ProjectID), currentOwner) {
+		return nil
+	}
+	if label != nil {
+		if err := client.DeleteLabel(ctx, label.ID); err != nil {
+			return err
+		}
+	}
+	owner.Events.orphaned("project", existingProject.Name, int64(existingProject.ProjectID))
+	return nil
+}
+
+// releaseRegistry removes the ownership marker of a registry the resource
+// owns, leaving the registry in Harbor.
+func releaseRegistry(ctx context.Context, registry harborconfigurationv1alpha1.Registry, owner ownership, client *harborClient) error {
+	srcRegistry, err := client.GetRegistryByName(ctx, registry.Name)
+	if err != nil {
+		return err
+	}
+	currentOwner := describedOwner(srcRegistry.Description)
+	if !owner.owns(srcRegistry.ID, currentOwner) {
+		return nil
+	}
+	if currentOwner != "" {
+		description := stripOwner(srcRegistry.Description)
+		if err := client.UpdateRegistry(ctx, &modelv2.RegistryUpdate{Description: &description}, srcRegistry.ID); err != nil {
+			return err
+		}
 	}
-	return ctrl.Result{}, err
+	owner.Events.orphaned("registry", registry.Name, srcRegistry.ID)
+	return nil
 }
@@ -0,0 +1,631 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	modelv2 "github.com/mittwald/goharbor-client/v5/apiv2/model"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	controllerutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+func TestReplicationFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters []harborconfigurationv1alpha1.ReplicationFilter
+		want    []*modelv2.ReplicationFilter
+	}{
+		{
+			name: "no filters",
+			want: []*modelv2.ReplicationFilter{},
+		},
+		{
+			name: "name and tag",
+			filters: []harborconfigurationv1alpha1.ReplicationFilter{
+				{Type: "name", Value: "library/**"},
+				{Type: "tag", Value: "v*", Decoration: "excludes"},
+			},
+			want: []*modelv2.ReplicationFilter{
+				{Type: "name", Value: "library/**"},
+				{Type: "tag", Value: "v*", Decoration: "excludes"},
+			},
+		},
+		{
+			name: "labels",
+			filters: []harborconfigurationv1alpha1.ReplicationFilter{
+				{Type: "label", Value: "release, stable ,approved", Decoration: "matches"},
+			},
+			want: []*modelv2.ReplicationFilter{
+				{Type: "label", Value: []string{"release", "stable", "approved"}, Decoration: "matches"},
+			},
+		},
+		{
+			name: "single label",
+			filters: []harborconfigurationv1alpha1.ReplicationFilter{
+				{Type: "label", Value: "release"},
+			},
+			want: []*modelv2.ReplicationFilter{
+				{Type: "label", Value: []string{"release"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replicationFilters(tt.filters); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("replicationFilters() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyProjectMetadata(t *testing.T) {
+	enabled, disabled := true, false
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name        string
+		existing    modelv2.ProjectMetadata
+		requested   *harborconfigurationv1alpha1.ProjectMetadata
+		want        modelv2.ProjectMetadata
+		wantDrifted []string
+	}{
+		{
+			name:     "nothing requested",
+			existing: modelv2.ProjectMetadata{Public: "true", AutoScan: str("true")},
+			want:     modelv2.ProjectMetadata{Public: "true", AutoScan: str("true")},
+		},
+		{
+			name:      "unchanged",
+			existing:  modelv2.ProjectMetadata{AutoScan: str("true"), Severity: str("high")},
+			requested: &harborconfigurationv1alpha1.ProjectMetadata{AutoScan: &enabled, Severity: "high"},
+			want:      modelv2.ProjectMetadata{AutoScan: str("true"), Severity: str("high")},
+		},
+		{
+			name:      "disabled settings Harbor leaves out",
+			requested: &harborconfigurationv1alpha1.ProjectMetadata{PreventVul: &disabled, EnableContentTrust: &disabled},
+		},
+		{
+			name:        "enabled",
+			existing:    modelv2.ProjectMetadata{AutoScan: str("false")},
+			requested:   &harborconfigurationv1alpha1.ProjectMetadata{AutoScan: &enabled, EnableContentTrustCosign: &enabled},
+			want:        modelv2.ProjectMetadata{AutoScan: str("true"), EnableContentTrustCosign: str("true")},
+			wantDrifted: []string{"autoScan (false -> true)", "enableContentTrustCosign ( -> true)"},
+		},
+		{
+			name:        "disabled",
+			existing:    modelv2.ProjectMetadata{PreventVul: str("true"), ReuseSysCVEAllowlist: str("true")},
+			requested:   &harborconfigurationv1alpha1.ProjectMetadata{PreventVul: &disabled},
+			want:        modelv2.ProjectMetadata{PreventVul: str("false"), ReuseSysCVEAllowlist: str("true")},
+			wantDrifted: []string{"preventVul (true -> false)"},
+		},
+		{
+			name:        "severity",
+			existing:    modelv2.ProjectMetadata{Severity: str("low")},
+			requested:   &harborconfigurationv1alpha1.ProjectMetadata{Severity: "critical"},
+			want:        modelv2.ProjectMetadata{Severity: str("critical")},
+			wantDrifted: []string{"severity (low -> critical)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata := tt.existing
+			drifted := applyProjectMetadata(&metadata, tt.requested)
+			if !reflect.DeepEqual(drifted, tt.wantDrifted) {
+				t.Errorf("applyProjectMetadata() = %q, want %q", drifted, tt.wantDrifted)
+			}
+			if !reflect.DeepEqual(metadata, tt.want) {
+				t.Errorf("metadata = %+v, want %+v", metadata, tt.want)
+			}
+		})
+	}
+}
+
+func TestHarborConfigurationReconcileDeleted(t *testing.T) {
+	r := &HarborConfigurationReconciler{Client: fake.NewClientBuilder().WithScheme(newTargetScheme(t)).Build()}
+
+	result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "team-a", Name: "gone"}})
+	if err != nil || !reflect.DeepEqual(result, ctrl.Result{}) {
+		t.Errorf("Reconcile() = %v, %v, want no requeue", result, err)
+	}
+}
+
+// fakeConfigurationHarbor serves the registries, projects and replication
+// rules of a Harbor, failing the requests for the names in failing.
+type fakeConfigurationHarbor struct {
+	mu         sync.Mutex
+	registries map[string]*modelv2.Registry
+	projects   map[string]*modelv2.Project
+	labels     map[int64]*modelv2.Label
+	policies   map[string]*modelv2.ReplicationPolicy
+	failing    map[string]bool
+	nextID     int64
+	// paths holds the path of every request.
+	paths []string
+}
+
+func (f *fakeConfigurationHarbor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/v2.0")
+	f.paths = append(f.paths, path)
+	query := r.URL.Query()
+	name := strings.TrimPrefix(query.Get("q"), "name=")
+	switch {
+	case r.Method == http.MethodGet && path == "/registries":
+		if f.failing[name] {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var registries []*modelv2.Registry
+		if registry, ok := f.registries[name]; ok && query.Get("page") == "1" {
+			registries = append(registries, registry)
+		}
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(registries)))
+		writeJSON(w, registries)
+	case r.Method == http.MethodPost && path == "/registries":
+		var registry modelv2.Registry
+		_ = json.NewDecoder(r.Body).Decode(&registry)
+		f.nextID++
+		registry.ID = f.nextID
+		f.registries[registry.Name] = &registry
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && strings.HasPrefix(path, "/registries/"):
+		var update modelv2.RegistryUpdate
+		_ = json.NewDecoder(r.Body).Decode(&update)
+		if registry := f.registry(path); registry != nil && update.Description != nil {
+			registry.Description = *update.Description
+		}
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/registries/"):
+		if registry := f.registry(path); registry != nil {
+			delete(f.registries, registry.Name)
+		}
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/projects/"):
+		project, ok := f.projects[strings.TrimPrefix(path, "/projects/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, project)
+	case r.Method == http.MethodPost && path == "/projects":
+		var request modelv2.ProjectReq
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		if f.failing[request.ProjectName] {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		f.nextID++
+		f.projects[request.ProjectName] = &modelv2.Project{ProjectID: int32(f.nextID), Name: request.ProjectName}
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/projects/"):
+		delete(f.projects, strings.TrimPrefix(path, "/projects/"))
+	case r.Method == http.MethodGet && path == "/labels":
+		var labels []*modelv2.Label
+		for _, label := range f.labels {
+			if strconv.FormatInt(label.ProjectID, 10) == query.Get("project_id") && query.Get("page") == "1" {
+				labels = append(labels, label)
+			}
+		}
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(labels)))
+		writeJSON(w, labels)
+	case r.Method == http.MethodPost && path == "/labels":
+		var label modelv2.Label
+		_ = json.NewDecoder(r.Body).Decode(&label)
+		f.nextID++
+		label.ID = f.nextID
+		f.labels[label.ID] = &label
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodGet && path == "/replication/policies":
+		var policies []*modelv2.ReplicationPolicy
+		if policy, ok := f.policies[name]; ok && query.Get("page") == "1" {
+			policies = append(policies, policy)
+		}
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(policies)))
+		writeJSON(w, policies)
+	case r.Method == http.MethodPost && path == "/replication/policies":
+		var policy modelv2.ReplicationPolicy
+		_ = json.NewDecoder(r.Body).Decode(&policy)
+		f.nextID++
+		policy.ID = f.nextID
+		f.policies[policy.Name] = &policy
+		w.WriteHeader(http.StatusCreated)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// registry returns the registry whose ID ends the path. f.mu must be held.
+func (f *fakeConfigurationHarbor) registry(path string) *modelv2.Registry {
+	id, _ := strconv.ParseInt(strings.TrimPrefix(path, "/registries/"), 10, 64)
+	for _, registry := range f.registries {
+		if registry.ID == id {
+			return registry
+		}
+	}
+	return nil
+}
+
+// registryOwner returns the owner named by the marker of the registry.
+func (f *fakeConfigurationHarbor) registryOwner(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return describedOwner(f.registries[name].Description)
+}
+
+// requested reports whether a request was sent to a path with the given prefix.
+func (f *fakeConfigurationHarbor) requested(prefix string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, path := range f.paths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// newConfigurationReconciler returns a reconciler for harborConfiguration,
+// whose Harbor target is the fake Harbor.
+func newConfigurationReconciler(t *testing.T, harborConfiguration *harborconfigurationv1alpha1.HarborConfiguration, harbor *fakeConfigurationHarbor, recorder record.EventRecorder) (*HarborConfigurationReconciler, client.Client) {
+	t.Helper()
+	server := httptest.NewServer(harbor)
+	t.Cleanup(server.Close)
+
+	harborConfiguration.Spec.HarborTarget = harborconfigurationv1alpha1.HarborTarget{
+		URL:                  server.URL,
+		CredentialsSecretRef: &harborconfigurationv1alpha1.LocalHarborCredentialsSecretReference{Name: "harbor-admin"},
+	}
+	c := fake.NewClientBuilder().WithScheme(newTargetScheme(t)).WithObjects(harborConfiguration, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: harborConfiguration.Namespace, Name: "harbor-admin"},
+		Data:       map[string][]byte{"username": []byte("admin"), "password": []byte("secret")},
+	}).Build()
+	return &HarborConfigurationReconciler{
+		Client:        c,
+		HarborClients: NewHarborClientPool(c, nil),
+		Recorder:      recorder,
+	}, c
+}
+
+func TestHarborConfigurationLegacyAdoption(t *testing.T) {
+	harbor := &fakeConfigurationHarbor{
+		registries: map[string]*modelv2.Registry{
+			"mirror": {ID: 1, Name: "mirror", Type: "docker-hub", URL: "https://hub.docker.com"},
+			"quay":   {ID: 2, Name: "quay", Type: "quay", URL: "https://quay.io"},
+		},
+		failing: map[string]bool{"quay": true},
+	}
+
+	// Reconciled by a version that neither marked the registries nor wrote
+	// the status.
+	harborConfiguration := &harborconfigurationv1alpha1.HarborConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:  "team-a",
+			Name:       "mirrors",
+			Generation: 1,
+			Finalizers: []string{harborFinaliserName},
+		},
+		Spec: harborconfigurationv1alpha1.HarborConfigurationSpec{
+			Registries: []harborconfigurationv1alpha1.Registry{
+				{Name: "mirror", Provider: "docker-hub", EndpointUrl: "https://hub.docker.com"},
+				{Name: "quay", Provider: "quay", EndpointUrl: "https://quay.io"},
+			},
+		},
+	}
+	r, c := newConfigurationReconciler(t, harborConfiguration, harbor, record.NewFakeRecorder(100))
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "team-a", Name: "mirrors"}}
+	owner := "HarborConfiguration team-a/mirrors"
+
+	if _, err := r.Reconcile(ctx, req); err == nil {
+		t.Fatal("Reconcile() succeeded while a registry could not be read")
+	}
+	var reconciled harborconfigurationv1alpha1.HarborConfiguration
+	if err := c.Get(ctx, req.NamespacedName, &reconciled); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if reconciled.Status.ObservedGeneration != 1 {
+		t.Errorf("observed generation = %d, want the status written", reconciled.Status.ObservedGeneration)
+	}
+	if _, ok := reconciled.Annotations[legacyAdoptionAnnotation]; !ok {
+		t.Error("legacy adoption cleared after a partial failure")
+	}
+	if got := harbor.registryOwner("mirror"); got != owner {
+		t.Errorf("owner of mirror = %q, want %q", got, owner)
+	}
+
+	harbor.mu.Lock()
+	harbor.failing = nil
+	harbor.mu.Unlock()
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if err := c.Get(ctx, req.NamespacedName, &reconciled); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if _, ok := reconciled.Annotations[legacyAdoptionAnnotation]; ok {
+		t.Error("legacy adoption kept after every registry was reconciled")
+	}
+	if got := harbor.registryOwner("quay"); got != owner {
+		t.Errorf("owner of quay = %q, want %q", got, owner)
+	}
+}
+
+func TestHarborConfigurationDeletion(t *testing.T) {
+	owner := "HarborConfiguration team-a/mirrors"
+	other := "HarborConfiguration team-b/mirrors"
+	now := metav1.Now()
+
+	tests := []struct {
+		name           string
+		deletionPolicy harborconfigurationv1alpha1.DeletionPolicy
+		failing        map[string]bool
+		wantErr        bool
+		wantRegistries map[string]string
+		wantFinalizer  bool
+		wantEvents     []string
+	}{
+		{
+			name:           "delete",
+			deletionPolicy: harborconfigurationv1alpha1.DeletionPolicyDelete,
+			wantRegistries: map[string]string{"theirs": other, "manual": ""},
+			wantEvents:     []string{"Normal " + harborconfigurationv1alpha1.ReasonDeleted},
+		},
+		{
+			name:           "orphan",
+			deletionPolicy: harborconfigurationv1alpha1.DeletionPolicyOrphan,
+			wantRegistries: map[string]string{"mine": "", "theirs": other, "manual": ""},
+			wantEvents:     []string{"Normal " + harborconfigurationv1alpha1.ReasonOrphaned},
+		},
+		{
+			name:           "harbor error",
+			deletionPolicy: harborconfigurationv1alpha1.DeletionPolicyDelete,
+			failing:        map[string]bool{"mine": true},
+			wantErr:        true,
+			wantRegistries: map[string]string{"mine": owner, "theirs": other, "manual": ""},
+			wantFinalizer:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			harbor := &fakeConfigurationHarbor{
+				registries: map[string]*modelv2.Registry{
+					"mine":   {ID: 1, Name: "mine", Description: withOwner("Docker Hub", owner)},
+					"theirs": {ID: 2, Name: "theirs", Description: withOwner("", other)},
+					"manual": {ID: 3, Name: "manual"},
+				},
+				failing: tt.failing,
+			}
+			harborConfiguration := &harborconfigurationv1alpha1.HarborConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:         "team-a",
+					Name:              "mirrors",
+					Generation:        1,
+					Finalizers:        []string{harborFinaliserName},
+					DeletionTimestamp: &now,
+				},
+				Spec: harborconfigurationv1alpha1.HarborConfigurationSpec{
+					Registries: []harborconfigurationv1alpha1.Registry{
+						{Name: "mine", Provider: "docker-hub"},
+						{Name: "theirs", Provider: "docker-hub"},
+						{Name: "manual", Provider: "docker-hub"},
+					},
+					DeletionPolicy: tt.deletionPolicy,
+				},
+				Status: harborconfigurationv1alpha1.HarborConfigurationStatus{
+					ObservedGeneration: 1,
+					Registries:         []harborconfigurationv1alpha1.RegistryStatus{{Name: "mine", ID: 1, Ready: true}},
+				},
+			}
+			recorder := record.NewFakeRecorder(10)
+			r, c := newConfigurationReconciler(t, harborConfiguration, harbor, recorder)
+			ctx := context.Background()
+
+			_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(harborConfiguration)})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Reconcile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			registries := make(map[string]string)
+			for name := range harbor.registries {
+				registries[name] = harbor.registryOwner(name)
+			}
+			if !reflect.DeepEqual(registries, tt.wantRegistries) {
+				t.Errorf("registries left with their owners = %v, want %v", registries, tt.wantRegistries)
+			}
+			if registry, ok := harbor.registries["mine"]; ok && stripOwner(registry.Description) != "Docker Hub" {
+				t.Errorf("description of mine = %q, want it kept", registry.Description)
+			}
+
+			var stored harborconfigurationv1alpha1.HarborConfiguration
+			err = c.Get(ctx, client.ObjectKeyFromObject(harborConfiguration), &stored)
+			hasFinalizer := err == nil && controllerutil.ContainsFinalizer(&stored, harborFinaliserName)
+			if hasFinalizer != tt.wantFinalizer {
+				t.Errorf("finalizer kept = %v, want %v", hasFinalizer, tt.wantFinalizer)
+			}
+
+			close(recorder.Events)
+			var events []string
+			for event := range recorder.Events {
+				if !strings.HasPrefix(event, "Warning") {
+					events = append(events, strings.Join(strings.Fields(event)[:2], " "))
+				}
+			}
+			if !reflect.DeepEqual(events, tt.wantEvents) {
+				t.Errorf("events = %q, want %q", events, tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestHarborConfigurationItemFailures(t *testing.T) {
+	harbor := &fakeConfigurationHarbor{
+		registries: map[string]*modelv2.Registry{},
+		projects:   map[string]*modelv2.Project{},
+		labels:     map[int64]*modelv2.Label{},
+		policies:   map[string]*modelv2.ReplicationPolicy{},
+		failing:    map[string]bool{"quay": true, "broken": true},
+	}
+	harborConfiguration := &harborconfigurationv1alpha1.HarborConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "mirrors", Generation: 1},
+		Spec: harborconfigurationv1alpha1.HarborConfigurationSpec{
+			Registries: []harborconfigurationv1alpha1.Registry{
+				{Name: "docker-hub", Provider: "docker-hub", EndpointUrl: "https://hub.docker.com"},
+				{Name: "quay", Provider: "quay", EndpointUrl: "https://quay.io"},
+			},
+			Projects: []harborconfigurationv1alpha1.ProjectReq{
+				{ProjectName: "team"},
+				{ProjectName: "broken"},
+			},
+			Replications: []harborconfigurationv1alpha1.Replication{
+				{Name: "pull", RegistryName: "docker-hub"},
+				// The registry of the rule does not exist in Harbor.
+				{Name: "push", RegistryName: "missing"},
+			},
+		},
+	}
+	r, c := newConfigurationReconciler(t, harborConfiguration, harbor, record.NewFakeRecorder(100))
+	ctx := context.Background()
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(harborConfiguration)}); err == nil {
+		t.Fatal("Reconcile() succeeded while items failed")
+	}
+	var stored harborconfigurationv1alpha1.HarborConfiguration
+	if err := c.Get(ctx, client.ObjectKeyFromObject(harborConfiguration), &stored); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	ready := make(map[string]bool)
+	for _, registryStatus := range stored.Status.Registries {
+		ready["registry/"+registryStatus.Name] = registryStatus.Ready && registryStatus.ID != 0 && registryStatus.Message == ""
+	}
+	for _, projectStatus := range stored.Status.Projects {
+		ready["project/"+projectStatus.Name] = projectStatus.Ready && projectStatus.ID != "" && projectStatus.Message == ""
+	}
+	for _, replicationStatus := range stored.Status.Replications {
+		ready["replication/"+replicationStatus.Name] = replicationStatus.Ready && replicationStatus.ID != 0 && replicationStatus.Message == ""
+	}
+	wantReady := map[string]bool{
+		"registry/docker-hub": true, "registry/quay": false,
+		"project/team": true, "project/broken": false,
+		"replication/pull": true, "replication/push": false,
+	}
+	if !reflect.DeepEqual(ready, wantReady) {
+		t.Errorf("items ready = %v, want %v", ready, wantReady)
+	}
+
+	for conditionType, names := range map[string][2]string{
+		harborconfigurationv1alpha1.ConditionRegistryReady:    {"quay", "docker-hub"},
+		harborconfigurationv1alpha1.ConditionProjectReady:     {"broken", "team"},
+		harborconfigurationv1alpha1.ConditionReplicationReady: {"push", "pull"},
+	} {
+		condition := meta.FindStatusCondition(stored.Status.Conditions, conditionType)
+		if condition == nil || condition.Status != metav1.ConditionFalse {
+			t.Errorf("%s condition = %+v, want False", conditionType, condition)
+			continue
+		}
+		if failed, reconciled := strconv.Quote(names[0]), strconv.Quote(names[1]); !strings.Contains(condition.Message, failed) || strings.Contains(condition.Message, reconciled) {
+			t.Errorf("%s message = %q, want only %s reported", conditionType, condition.Message, failed)
+		}
+	}
+
+	if _, ok := harbor.registries["docker-hub"]; !ok {
+		t.Error("registry docker-hub was not created")
+	}
+	if _, ok := harbor.projects["team"]; !ok {
+		t.Error("project team was not created")
+	}
+	if _, ok := harbor.policies["pull"]; !ok {
+		t.Error("replication rule pull was not created")
+	}
+}
+
+func TestHarborConfigurationOmittedSections(t *testing.T) {
+	owner := "HarborConfiguration team-a/proxy-cache"
+	harbor := &fakeConfigurationHarbor{
+		// Objects the resource declared before its spec was narrowed down
+		// to a single project.
+		registries: map[string]*modelv2.Registry{
+			"docker-hub": {ID: 1, Name: "docker-hub", Description: withOwner("", owner)},
+		},
+		projects: map[string]*modelv2.Project{},
+		labels:   map[int64]*modelv2.Label{},
+		policies: map[string]*modelv2.ReplicationPolicy{
+			"pull": {ID: 2, Name: "pull", Description: withOwner("", owner)},
+		},
+		nextID: 2,
+	}
+	harborConfiguration := &harborconfigurationv1alpha1.HarborConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "proxy-cache", Generation: 1},
+		Spec: harborconfigurationv1alpha1.HarborConfigurationSpec{
+			ProjectReq: &harborconfigurationv1alpha1.ProjectReq{ProjectName: "team"},
+		},
+	}
+	r, c := newConfigurationReconciler(t, harborConfiguration, harbor, record.NewFakeRecorder(100))
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(harborConfiguration)}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if _, ok := harbor.projects["team"]; !ok {
+		t.Error("project team was not created")
+	}
+
+	var stored harborconfigurationv1alpha1.HarborConfiguration
+	if err := c.Get(ctx, req.NamespacedName, &stored); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if err := c.Delete(ctx, &stored); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if _, ok := harbor.projects["team"]; ok {
+		t.Error("project team was not deleted")
+	}
+	if err := c.Get(ctx, req.NamespacedName, &stored); err == nil {
+		t.Errorf("Reconcile() kept the finalizers %v", stored.Finalizers)
+	}
+
+	for _, prefix := range []string{"/registries", "/replication"} {
+		if harbor.requested(prefix) {
+			t.Errorf("request sent to %s, which the spec does not declare", prefix)
+		}
+	}
+	if len(harbor.registries) != 1 || len(harbor.policies) != 1 {
+		t.Errorf("registries = %v, replication rules = %v, want them left alone", harbor.registries, harbor.policies)
+	}
+}
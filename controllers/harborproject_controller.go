@@ -0,0 +1,186 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	harborerrors "github.com/mittwald/goharbor-client/v5/apiv2/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	controllerutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+const proxyCacheRegistryRefField = ".spec.proxyCacheRegistryRef"
+
+// HarborProjectReconciler reconciles a HarborProject object
+type HarborProjectReconciler struct {
+	client.Client
+	*runtime.Scheme
+	ClientSet     *kubernetes.Clientset
+	HarborClients *HarborClientPool
+	Recorder      record.EventRecorder
+	// ResyncInterval is how often Harbor is checked for drift from the spec.
+	ResyncInterval time.Duration
+}
+
+//+kubebuilder:rbac:groups=administration.harbor.configuration,resources=harborprojects,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=administration.harbor.configuration,resources=harborprojects/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=administration.harbor.configuration,resources=harborprojects/finalizers,verbs=update
+
+func (r *HarborProjectReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var harborProject harborconfigurationv1alpha1.HarborProject
+	err := r.Get(ctx, req.NamespacedName, &harborProject)
+	if err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	project := harborconfigurationv1alpha1.ProjectReq{
+		ProjectName:     harborProject.HarborProjectName(),
+		ProjectSettings: harborProject.Spec.ProjectSettings,
+	}
+	knownID, _ := strconv.ParseInt(harborProject.Status.ID, 10, 64)
+	owner := newOwnership("HarborProject", &harborProject, harborProject.Spec.AdoptionPolicy, knownID, r.Recorder)
+
+	if !harborProject.ObjectMeta.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(&harborProject, harborFinaliserName) {
+			return ctrl.Result{}, nil
+		}
+
+		orphan := project.DeletionPolicy == harborconfigurationv1alpha1.DeletionPolicyOrphan
+		client, err := r.HarborClients.Get(ctx, harborProject.Namespace, harborProject.Spec.HarborTarget)
+		if err != nil {
+			return finalizeWithoutHarbor(ctx, r.Client, owner.Events, &harborProject, orphan, err)
+		}
+		if orphan {
+			err = releaseProject(ctx, project, owner, client)
+			if err != nil && !errors.Is(err, &harborerrors.ErrProjectNotFound{}) {
+				owner.Events.releaseFailed("project", project.ProjectName, err)
+			}
+		} else {
+			_, err = deleteProject(ctx, project, owner, client)
+			if err != nil && !errors.Is(err, &harborerrors.ErrProjectNotFound{}) {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, removeFinalizer(ctx, r.Client, &harborProject)
+	}
+
+	resource := harborProjectResource(&harborProject)
+	client, err := r.HarborClients.Get(ctx, harborProject.Namespace, harborProject.Spec.HarborTarget)
+	if err != nil {
+		return resource.harborUnavailable(ctx, r.Client, err)
+	}
+
+	if err := addFinalizer(ctx, r.Client, &harborProject); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if harborProject.Spec.ProxyCacheRegistryRef != "" {
+		registryName, err := readyRegistryName(ctx, r.Client, harborProject.Namespace, harborProject.Spec.ProxyCacheRegistryRef)
+		if err != nil {
+			// The HarborRegistry watch requeues the project once the registry is ready.
+			resource.setReady(v1.ConditionFalse, harborconfigurationv1alpha1.ReasonRegistryNotReady, err.Error())
+			return ctrl.Result{}, resource.updateStatus(ctx, r.Client)
+		}
+		project.ProxyCacheRegistryName = registryName
+	}
+
+	reconciled := resource.reconciled()
+	id, drifted, err := reconcileProject(ctx, project, owner, client)
+	if err != nil {
+		resource.setReady(v1.ConditionFalse, harborconfigurationv1alpha1.ReasonReconcileFailed, err.Error())
+	} else {
+		harborProject.Status.ID = id
+		resource.setReady(v1.ConditionTrue, harborconfigurationv1alpha1.ReasonReconciled, "")
+		var drift []string
+		if reconciled && len(drifted) > 0 {
+			drift = append(drift, driftMessage("project", project.ProjectName, drifted))
+		}
+		reportDrift(r.Recorder, &harborProject, &harborProject.Status.Conditions, drift)
+	}
+	reportConflicts(&harborProject, &harborProject.Status.Conditions, []error{err})
+
+	return resource.finish(ctx, r.Client, ctrl.Result{}, r.ResyncInterval, err)
+}
+
+// harborProjectResource returns the status of the HarborProject.
+func harborProjectResource(harborProject *harborconfigurationv1alpha1.HarborProject) harborResource {
+	return harborResource{
+		object:             harborProject,
+		conditions:         &harborProject.Status.Conditions,
+		observedGeneration: &harborProject.Status.ObservedGeneration,
+		kind:               "project",
+		managed:            func() bool { return harborProject.Status.ID != "" },
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HarborProjectReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	err := mgr.GetFieldIndexer().IndexField(context.Background(), &harborconfigurationv1alpha1.HarborProject{}, proxyCacheRegistryRefField, func(obj client.Object) []string {
+		harborProject := obj.(*harborconfigurationv1alpha1.HarborProject)
+		if harborProject.Spec.ProxyCacheRegistryRef == "" {
+			return nil
+		}
+		return []string{harborProject.Spec.ProxyCacheRegistryRef}
+	})
+	if err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&harborconfigurationv1alpha1.HarborProject{}).
+		Watches(&source.Kind{Type: &harborconfigurationv1alpha1.HarborRegistry{}}, handler.EnqueueRequestsFromMapFunc(r.findProjectsForRegistry)).
+		Complete(r)
+}
+
+// findProjectsForRegistry maps a HarborRegistry to the HarborProjects using
+// it as their proxy cache.
+func (r *HarborProjectReconciler) findProjectsForRegistry(harborRegistry client.Object) []reconcile.Request {
+	return listRequests(r.Client, &harborconfigurationv1alpha1.HarborProjectList{}, client.InNamespace(harborRegistry.GetNamespace()), client.MatchingFields{
+		proxyCacheRegistryRefField: harborRegistry.GetName(),
+	})
+}
+
+// readyRegistryName returns the Harbor name of the referenced HarborRegistry
+// once it has been reconciled.
+func readyRegistryName(ctx context.Context, kubeClient client.Client, namespace, name string) (string, error) {
+	var harborRegistry harborconfigurationv1alpha1.HarborRegistry
+	err := kubeClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &harborRegistry)
+	if err != nil {
+		return "", err
+	}
+	if !meta.IsStatusConditionTrue(harborRegistry.Status.Conditions, harborconfigurationv1alpha1.ConditionReady) || !harborRegistry.DeletionTimestamp.IsZero() {
+		return "", fmt.Errorf("HarborRegistry %s/%s is not ready", namespace, name)
+	}
+	return harborRegistry.RegistryName(), nil
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+func TestHarborProjectDeletionWithoutTarget(t *testing.T) {
+	now := metav1.Now()
+	tests := []struct {
+		name           string
+		deletionPolicy harborconfigurationv1alpha1.DeletionPolicy
+	}{
+		{
+			name:           "delete",
+			deletionPolicy: harborconfigurationv1alpha1.DeletionPolicyDelete,
+		},
+		{
+			name:           "orphan",
+			deletionPolicy: harborconfigurationv1alpha1.DeletionPolicyOrphan,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			harborProject := &harborconfigurationv1alpha1.HarborProject{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:         "team-a",
+					Name:              "images",
+					Finalizers:        []string{harborFinaliserName},
+					DeletionTimestamp: &now,
+				},
+				Spec: harborconfigurationv1alpha1.HarborProjectSpec{
+					// The credentials Secret went away with the namespace.
+					HarborTarget: harborconfigurationv1alpha1.HarborTarget{
+						URL:                  "https://harbor.example.com",
+						CredentialsSecretRef: &harborconfigurationv1alpha1.LocalHarborCredentialsSecretReference{Name: "harbor-admin"},
+					},
+				},
+			}
+			harborProject.Spec.DeletionPolicy = tt.deletionPolicy
+			c := fake.NewClientBuilder().WithScheme(newTargetScheme(t)).WithObjects(harborProject).Build()
+			r := &HarborProjectReconciler{
+				Client:        c,
+				HarborClients: NewHarborClientPool(c, nil),
+				Recorder:      record.NewFakeRecorder(1),
+			}
+
+			_, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(harborProject)})
+			if err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+			var stored harborconfigurationv1alpha1.HarborProject
+			err = c.Get(context.Background(), client.ObjectKeyFromObject(harborProject), &stored)
+			if err == nil && len(stored.Finalizers) > 0 {
+				t.Errorf("Reconcile() kept the finalizers %v", stored.Finalizers)
+			}
+		})
+	}
+}
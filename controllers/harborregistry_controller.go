@@ -0,0 +1,203 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	harborerrors "github.com/mittwald/goharbor-client/v5/apiv2/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	controllerutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+// registryInUseRequeueAfter is how long a HarborRegistry that is still
+// referenced waits before its deletion is retried.
+const registryInUseRequeueAfter = 30 * time.Second
+
+// HarborRegistryReconciler reconciles a HarborRegistry object
+type HarborRegistryReconciler struct {
+	client.Client
+	*runtime.Scheme
+	ClientSet     *kubernetes.Clientset
+	HarborClients *HarborClientPool
+	Recorder      record.EventRecorder
+	// ResyncInterval is how often Harbor is checked for drift from the spec.
+	ResyncInterval time.Duration
+}
+
+//+kubebuilder:rbac:groups=administration.harbor.configuration,resources=harborregistries,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=administration.harbor.configuration,resources=harborregistries/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=administration.harbor.configuration,resources=harborregistries/finalizers,verbs=update
+
+func (r *HarborRegistryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var harborRegistry harborconfigurationv1alpha1.HarborRegistry
+	err := r.Get(ctx, req.NamespacedName, &harborRegistry)
+	if err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	resource := harborRegistryResource(&harborRegistry)
+	registry := harborRegistry.Spec.Registry
+	registry.Name = harborRegistry.RegistryName()
+	owner := newOwnership("HarborRegistry", &harborRegistry, harborRegistry.Spec.AdoptionPolicy, harborRegistry.Status.ID, r.Recorder)
+
+	if !harborRegistry.ObjectMeta.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(&harborRegistry, harborFinaliserName) {
+			return ctrl.Result{}, nil
+		}
+		orphan := registry.DeletionPolicy == harborconfigurationv1alpha1.DeletionPolicyOrphan
+		if !orphan {
+			users, err := r.registryUsers(ctx, &harborRegistry)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if len(users) > 0 {
+				resource.setReady(v1.ConditionFalse, harborconfigurationv1alpha1.ReasonRegistryInUse, fmt.Sprintf("registry is still referenced by %v", users))
+				if err := resource.updateStatus(ctx, r.Client); err != nil {
+					return ctrl.Result{}, err
+				}
+				return ctrl.Result{RequeueAfter: registryInUseRequeueAfter}, nil
+			}
+		}
+
+		client, err := r.HarborClients.Get(ctx, harborRegistry.Namespace, harborRegistry.Spec.HarborTarget)
+		if err != nil {
+			return finalizeWithoutHarbor(ctx, r.Client, owner.Events, &harborRegistry, orphan, err)
+		}
+		if orphan {
+			err = releaseRegistry(ctx, registry, owner, client)
+			if err != nil && !errors.Is(err, &harborerrors.ErrRegistryNotFound{}) {
+				owner.Events.releaseFailed("registry", registry.Name, err)
+			}
+		} else {
+			_, err = deleteRegistry(ctx, registry, owner, client)
+			if err != nil && !errors.Is(err, &harborerrors.ErrRegistryNotFound{}) {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, removeFinalizer(ctx, r.Client, &harborRegistry)
+	}
+
+	client, err := r.HarborClients.Get(ctx, harborRegistry.Namespace, harborRegistry.Spec.HarborTarget)
+	if err != nil {
+		return resource.harborUnavailable(ctx, r.Client, err)
+	}
+
+	if err := addFinalizer(ctx, r.Client, &harborRegistry); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	reconciled := resource.reconciled()
+	id, drifted, err := reconcileRegistry(ctx, r.ClientSet, harborRegistry.Namespace, registry, owner, client)
+	if err != nil {
+		resource.setReady(v1.ConditionFalse, harborconfigurationv1alpha1.ReasonReconcileFailed, err.Error())
+	} else {
+		harborRegistry.Status.ID = id
+		resource.setReady(v1.ConditionTrue, harborconfigurationv1alpha1.ReasonReconciled, "")
+		var drift []string
+		if reconciled && len(drifted) > 0 {
+			drift = append(drift, driftMessage("registry", registry.Name, drifted))
+		}
+		reportDrift(r.Recorder, &harborRegistry, &harborRegistry.Status.Conditions, drift)
+	}
+	reportConflicts(&harborRegistry, &harborRegistry.Status.Conditions, []error{err})
+
+	return resource.finish(ctx, r.Client, ctrl.Result{}, r.ResyncInterval, err)
+}
+
+// registryUsers lists the HarborProjects and HarborReplicationPolicies that
+// still reference the registry.
+func (r *HarborRegistryReconciler) registryUsers(ctx context.Context, harborRegistry *harborconfigurationv1alpha1.HarborRegistry) ([]string, error) {
+	var users []string
+
+	var harborProjects harborconfigurationv1alpha1.HarborProjectList
+	err := r.List(ctx, &harborProjects, client.InNamespace(harborRegistry.Namespace), client.MatchingFields{proxyCacheRegistryRefField: harborRegistry.Name})
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range harborProjects.Items {
+		users = append(users, "HarborProject/"+item.Name)
+	}
+
+	var harborReplicationPolicies harborconfigurationv1alpha1.HarborReplicationPolicyList
+	err = r.List(ctx, &harborReplicationPolicies, client.InNamespace(harborRegistry.Namespace), client.MatchingFields{registryRefField: harborRegistry.Name})
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range harborReplicationPolicies.Items {
+		users = append(users, "HarborReplicationPolicy/"+item.Name)
+	}
+
+	return users, nil
+}
+
+// harborRegistryResource returns the status of the HarborRegistry.
+func harborRegistryResource(harborRegistry *harborconfigurationv1alpha1.HarborRegistry) harborResource {
+	return harborResource{
+		object:             harborRegistry,
+		conditions:         &harborRegistry.Status.Conditions,
+		observedGeneration: &harborRegistry.Status.ObservedGeneration,
+		kind:               "registry",
+		managed:            func() bool { return harborRegistry.Status.ID != 0 },
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HarborRegistryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	err := mgr.GetFieldIndexer().IndexField(context.Background(), &harborconfigurationv1alpha1.HarborRegistry{}, credentialSecretRefField, func(obj client.Object) []string {
+		harborRegistry := obj.(*harborconfigurationv1alpha1.HarborRegistry)
+		if harborRegistry.Spec.Credential == nil || harborRegistry.Spec.Credential.SecretRef == nil {
+			return nil
+		}
+		return []string{types.NamespacedName{
+			Namespace: harborRegistry.Namespace,
+			Name:      harborRegistry.Spec.Credential.SecretRef.Name,
+		}.String()}
+	})
+	if err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&harborconfigurationv1alpha1.HarborRegistry{}).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.findRegistriesForSecret), builder.OnlyMetadata).
+		Complete(r)
+}
+
+// findRegistriesForSecret maps a Secret to the HarborRegistries whose
+// credential references it.
+func (r *HarborRegistryReconciler) findRegistriesForSecret(secret client.Object) []reconcile.Request {
+	return listRequests(r.Client, &harborconfigurationv1alpha1.HarborRegistryList{}, client.MatchingFields{
+		credentialSecretRefField: client.ObjectKeyFromObject(secret).String(),
+	})
+}
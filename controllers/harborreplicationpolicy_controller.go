@@ -0,0 +1,174 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	harborerrors "github.com/mittwald/goharbor-client/v5/apiv2/pkg/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	controllerutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+const registryRefField = ".spec.registryRef"
+
+// HarborReplicationPolicyReconciler reconciles a HarborReplicationPolicy object
+type HarborReplicationPolicyReconciler struct {
+	client.Client
+	*runtime.Scheme
+	ClientSet     *kubernetes.Clientset
+	HarborClients *HarborClientPool
+	Recorder      record.EventRecorder
+	// ResyncInterval is how often Harbor is checked for drift from the spec.
+	ResyncInterval time.Duration
+}
+
+//+kubebuilder:rbac:groups=administration.harbor.configuration,resources=harborreplicationpolicies,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=administration.harbor.configuration,resources=harborreplicationpolicies/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=administration.harbor.configuration,resources=harborreplicationpolicies/finalizers,verbs=update
+
+func (r *HarborReplicationPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var harborReplicationPolicy harborconfigurationv1alpha1.HarborReplicationPolicy
+	err := r.Get(ctx, req.NamespacedName, &harborReplicationPolicy)
+	if err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	replication := harborconfigurationv1alpha1.Replication{
+		Name:                harborReplicationPolicy.PolicyName(),
+		ReplicationSettings: harborReplicationPolicy.Spec.ReplicationSettings,
+	}
+	owner := newOwnership("HarborReplicationPolicy", &harborReplicationPolicy, harborReplicationPolicy.Spec.AdoptionPolicy, harborReplicationPolicy.Status.ID, r.Recorder)
+
+	if !harborReplicationPolicy.ObjectMeta.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(&harborReplicationPolicy, harborFinaliserName) {
+			return ctrl.Result{}, nil
+		}
+
+		orphan := replication.DeletionPolicy == harborconfigurationv1alpha1.DeletionPolicyOrphan
+		client, err := r.HarborClients.Get(ctx, harborReplicationPolicy.Namespace, harborReplicationPolicy.Spec.HarborTarget)
+		if err != nil {
+			return finalizeWithoutHarbor(ctx, r.Client, owner.Events, &harborReplicationPolicy, orphan, err)
+		}
+		if orphan {
+			err = releaseReplicationRule(ctx, replication, owner, client)
+			if err != nil && !errors.Is(err, &harborerrors.ErrNotFound{}) {
+				owner.Events.releaseFailed("replication rule", replication.Name, err)
+			}
+		} else {
+			_, err = deleteReplicationRule(ctx, replication, owner, client)
+			if err != nil && !errors.Is(err, &harborerrors.ErrNotFound{}) {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, removeFinalizer(ctx, r.Client, &harborReplicationPolicy)
+	}
+
+	resource := harborReplicationPolicyResource(&harborReplicationPolicy)
+	client, err := r.HarborClients.Get(ctx, harborReplicationPolicy.Namespace, harborReplicationPolicy.Spec.HarborTarget)
+	if err != nil {
+		return resource.harborUnavailable(ctx, r.Client, err)
+	}
+
+	if err := addFinalizer(ctx, r.Client, &harborReplicationPolicy); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	registryName, err := readyRegistryName(ctx, r.Client, harborReplicationPolicy.Namespace, harborReplicationPolicy.Spec.RegistryRef)
+	if err != nil {
+		// The HarborRegistry watch requeues the policy once the registry is ready.
+		resource.setReady(v1.ConditionFalse, harborconfigurationv1alpha1.ReasonRegistryNotReady, err.Error())
+		return ctrl.Result{}, resource.updateStatus(ctx, r.Client)
+	}
+	replication.RegistryName = registryName
+
+	reconciled := resource.reconciled()
+	id, drifted, err := reconcileReplication(ctx, replication, owner, client)
+	if err != nil {
+		resource.setReady(v1.ConditionFalse, harborconfigurationv1alpha1.ReasonReconcileFailed, err.Error())
+	} else if err = runReplicationIfRequested(ctx, replication, id, &harborReplicationPolicy.Status.ReplicationRunStatus, owner.Events, client); err != nil {
+		harborReplicationPolicy.Status.ID = id
+		resource.setReady(v1.ConditionFalse, harborconfigurationv1alpha1.ReasonReplicationTriggerFailed, err.Error())
+	} else if err = refreshReplicationExecution(ctx, replication.Name, &harborReplicationPolicy.Status.ReplicationRunStatus, client); err != nil {
+		harborReplicationPolicy.Status.ID = id
+		resource.setReady(v1.ConditionFalse, harborconfigurationv1alpha1.ReasonReconcileFailed, err.Error())
+	} else {
+		harborReplicationPolicy.Status.ID = id
+		harborReplicationPolicy.Status.NextScheduledTime = nextScheduledTime(harborReplicationPolicy.Spec.TriggerMode, time.Now())
+		resource.setReady(v1.ConditionTrue, harborconfigurationv1alpha1.ReasonReconciled, "")
+		var drift []string
+		if reconciled && len(drifted) > 0 {
+			drift = append(drift, driftMessage("replication", replication.Name, drifted))
+		}
+		reportDrift(r.Recorder, &harborReplicationPolicy, &harborReplicationPolicy.Status.Conditions, drift)
+	}
+	reportConflicts(&harborReplicationPolicy, &harborReplicationPolicy.Status.Conditions, []error{err})
+
+	runStatuses := map[string]harborconfigurationv1alpha1.ReplicationRunStatus{
+		replication.Name: harborReplicationPolicy.Status.ReplicationRunStatus,
+	}
+	result := ctrl.Result{RequeueAfter: setReplicationSucceededCondition(&harborReplicationPolicy.Status.Conditions, harborReplicationPolicy.Generation, runStatuses)}
+
+	return resource.finish(ctx, r.Client, result, r.ResyncInterval, err)
+}
+
+// harborReplicationPolicyResource returns the status of the HarborReplicationPolicy.
+func harborReplicationPolicyResource(harborReplicationPolicy *harborconfigurationv1alpha1.HarborReplicationPolicy) harborResource {
+	return harborResource{
+		object:             harborReplicationPolicy,
+		conditions:         &harborReplicationPolicy.Status.Conditions,
+		observedGeneration: &harborReplicationPolicy.Status.ObservedGeneration,
+		kind:               "replication",
+		managed:            func() bool { return harborReplicationPolicy.Status.ID != 0 },
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HarborReplicationPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	err := mgr.GetFieldIndexer().IndexField(context.Background(), &harborconfigurationv1alpha1.HarborReplicationPolicy{}, registryRefField, func(obj client.Object) []string {
+		harborReplicationPolicy := obj.(*harborconfigurationv1alpha1.HarborReplicationPolicy)
+		return []string{harborReplicationPolicy.Spec.RegistryRef}
+	})
+	if err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&harborconfigurationv1alpha1.HarborReplicationPolicy{}).
+		Watches(&source.Kind{Type: &harborconfigurationv1alpha1.HarborRegistry{}}, handler.EnqueueRequestsFromMapFunc(r.findPoliciesForRegistry)).
+		Complete(r)
+}
+
+// findPoliciesForRegistry maps a HarborRegistry to the HarborReplicationPolicies
+// replicating from it.
+func (r *HarborReplicationPolicyReconciler) findPoliciesForRegistry(harborRegistry client.Object) []reconcile.Request {
+	return listRequests(r.Client, &harborconfigurationv1alpha1.HarborReplicationPolicyList{}, client.InNamespace(harborRegistry.GetNamespace()), client.MatchingFields{
+		registryRefField: harborRegistry.GetName(),
+	})
+}
@@ -0,0 +1,567 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	modelv2 "github.com/mittwald/goharbor-client/v5/apiv2/model"
+	harborerrors "github.com/mittwald/goharbor-client/v5/apiv2/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	controllerutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+const (
+	// robotAccountPrefix is the prefix Harbor gives the names of robot accounts.
+	robotAccountPrefix = "robot$"
+
+	// defaultRobotAccountRefreshBefore is how long before expiry the
+	// credentials of a robot account are replaced when the spec sets no
+	// refreshBefore.
+	defaultRobotAccountRefreshBefore = 24 * time.Hour
+)
+
+// HarborRobotAccountReconciler reconciles a HarborRobotAccount object
+type HarborRobotAccountReconciler struct {
+	client.Client
+	*runtime.Scheme
+	HarborClients *HarborClientPool
+	Recorder      record.EventRecorder
+	// ResyncInterval is how often Harbor is checked for drift from the spec.
+	ResyncInterval time.Duration
+	// APIReader reads Secrets and ServiceAccounts, which are only watched by
+	// their metadata and therefore not in the cache of the Client.
+	APIReader client.Reader
+	// AllowSystemRobotAccounts allows robot accounts with level system, which
+	// can be granted permissions on every project in Harbor.
+	AllowSystemRobotAccounts bool
+}
+
+// dockerConfigJSON is the content of a kubernetes.io/dockerconfigjson Secret.
+type dockerConfigJSON struct {
+	Auths map[string]dockerConfigAuth `json:"auths"`
+}
+
+type dockerConfigAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Auth     string `json:"auth"`
+}
+
+//+kubebuilder:rbac:groups=administration.harbor.configuration,resources=harborrobotaccounts,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=administration.harbor.configuration,resources=harborrobotaccounts/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=administration.harbor.configuration,resources=harborrobotaccounts/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;update;patch
+
+func (r *HarborRobotAccountReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var harborRobotAccount harborconfigurationv1alpha1.HarborRobotAccount
+	err := r.Get(ctx, req.NamespacedName, &harborRobotAccount)
+	if err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	owner := newOwnership("HarborRobotAccount", &harborRobotAccount, harborRobotAccount.Spec.AdoptionPolicy, harborRobotAccount.Status.ID, r.Recorder)
+
+	if !harborRobotAccount.ObjectMeta.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(&harborRobotAccount, harborFinaliserName) {
+			return ctrl.Result{}, nil
+		}
+
+		// The Secret is garbage collected through its owner reference, its
+		// copies in other namespaces are not.
+		if err := r.removeSecretCopies(ctx, &harborRobotAccount, nil); err != nil {
+			return ctrl.Result{}, err
+		}
+		if harborRobotAccount.Spec.PatchDefaultServiceAccount {
+			if err := r.setImagePullSecret(ctx, harborRobotAccount.Namespace, harborRobotAccount.CredentialsSecretName(), false); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		orphan := harborRobotAccount.Spec.DeletionPolicy == harborconfigurationv1alpha1.DeletionPolicyOrphan
+		client, err := r.HarborClients.Get(ctx, harborRobotAccount.Namespace, harborRobotAccount.Spec.HarborTarget)
+		if err != nil {
+			return finalizeWithoutHarbor(ctx, r.Client, owner.Events, &harborRobotAccount, orphan, err)
+		}
+		existing, err := client.GetRobotAccountByName(ctx, robotAccountName(&harborRobotAccount))
+		if orphan {
+			if err == nil {
+				err = releaseRobotAccount(ctx, existing, owner, client)
+			}
+			if err != nil && !errors.Is(err, &harborerrors.ErrRobotAccountUnknownResource{}) {
+				owner.Events.releaseFailed("robot account", robotAccountName(&harborRobotAccount), err)
+			}
+		} else {
+			if err == nil {
+				err = deleteRobotAccount(ctx, existing, owner, client)
+			}
+			if err != nil && !errors.Is(err, &harborerrors.ErrRobotAccountUnknownResource{}) {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, removeFinalizer(ctx, r.Client, &harborRobotAccount)
+	}
+
+	resource := harborRobotAccountResource(&harborRobotAccount)
+	client, err := r.HarborClients.Get(ctx, harborRobotAccount.Namespace, harborRobotAccount.Spec.HarborTarget)
+	if err != nil {
+		return resource.harborUnavailable(ctx, r.Client, err)
+	}
+
+	if err := addFinalizer(ctx, r.Client, &harborRobotAccount); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	registry := harborRobotAccount.Spec.Registry
+	if registry == "" {
+		registry, err = r.HarborClients.Registry(ctx, harborRobotAccount.Namespace, harborRobotAccount.Spec.HarborTarget)
+	}
+
+	reconciled := resource.reconciled()
+	var drifted []string
+	if err == nil {
+		drifted, err = r.reconcileRobotAccount(ctx, &harborRobotAccount, registry, owner, client)
+	}
+
+	var result ctrl.Result
+	var forbidden *robotAccountForbiddenError
+	if errors.As(err, &forbidden) {
+		resource.setReady(v1.ConditionFalse, harborconfigurationv1alpha1.ReasonRobotAccountForbidden, err.Error())
+	} else if err != nil {
+		resource.setReady(v1.ConditionFalse, harborconfigurationv1alpha1.ReasonReconcileFailed, err.Error())
+	} else if harborRobotAccount.Status.Namespaces, err = r.distributeSecret(ctx, &harborRobotAccount); err != nil {
+		resource.setReady(v1.ConditionFalse, harborconfigurationv1alpha1.ReasonSecretDistributionFailed, err.Error())
+	} else {
+		resource.setReady(v1.ConditionTrue, harborconfigurationv1alpha1.ReasonReconciled, "")
+		var drift []string
+		if reconciled && len(drifted) > 0 {
+			drift = append(drift, driftMessage("robot account", harborRobotAccount.Status.RobotName, drifted))
+		}
+		reportDrift(r.Recorder, &harborRobotAccount, &harborRobotAccount.Status.Conditions, drift)
+
+		if refreshAt := robotAccountRefreshTime(&harborRobotAccount); !refreshAt.IsZero() {
+			result.RequeueAfter = time.Until(refreshAt)
+			if result.RequeueAfter < time.Second {
+				result.RequeueAfter = time.Second
+			}
+		}
+	}
+	reportConflicts(&harborRobotAccount, &harborRobotAccount.Status.Conditions, []error{err})
+
+	return resource.finish(ctx, r.Client, result, r.ResyncInterval, err)
+}
+
+// reconcileRobotAccount creates the robot account or brings it back in line
+// with the spec and keeps its credentials in the Secret, returning the fields
+// that had drifted in Harbor.
+func (r *HarborRobotAccountReconciler) reconcileRobotAccount(ctx context.Context, harborRobotAccount *harborconfigurationv1alpha1.HarborRobotAccount, registry string, owner ownership, client *harborClient) ([]string, error) {
+	name := robotAccountName(harborRobotAccount)
+	fullName := robotAccountPrefix + name
+
+	requested, err := robotAccountRequest(harborRobotAccount, owner.Owner)
+	if err == nil {
+		err = r.checkRobotAccountScope(ctx, harborRobotAccount, requested, client)
+	}
+	if err != nil {
+		owner.Events.failed("robot account", fullName, err)
+		return nil, err
+	}
+
+	// Harbor cannot rename a robot account or change its level, so the
+	// account recorded in the status is replaced by a new one.
+	status := harborRobotAccount.Status
+	if status.ID != 0 && status.RobotName != "" && status.RobotName != fullName {
+		previous, err := client.GetRobotAccountByID(ctx, status.ID)
+		if err == nil && previous.Name == status.RobotName {
+			err = deleteRobotAccount(ctx, previous, owner, client)
+		}
+		if err != nil && !errors.Is(err, &harborerrors.ErrRobotAccountUnknownResource{}) {
+			owner.Events.failed("robot account", fullName, err)
+			return nil, err
+		}
+	}
+
+	drifted, err := r.robotAccountReconciliation(ctx, harborRobotAccount, name, requested, registry, owner, client)
+	if err != nil {
+		owner.Events.failed("robot account", fullName, err)
+		return nil, err
+	}
+	owner.Events.reconciled("robot account", fullName, harborRobotAccount.Status.ID, drifted)
+	return drifted, nil
+}
+
+func (r *HarborRobotAccountReconciler) robotAccountReconciliation(ctx context.Context, harborRobotAccount *harborconfigurationv1alpha1.HarborRobotAccount, name string, requested *modelv2.RobotCreate, registry string, owner ownership, client *harborClient) ([]string, error) {
+	existing, err := client.GetRobotAccountByName(ctx, name)
+	if errors.Is(err, &harborerrors.ErrRobotAccountUnknownResource{}) {
+		return []string{driftDeleted}, r.createRobotAccount(ctx, harborRobotAccount, requested, registry, client)
+	}
+	if err != nil {
+		return nil, err
+	}
+	currentOwner := describedOwner(existing.Description)
+	if err := owner.claim("robot account", existing.Name, existing.ID, currentOwner); err != nil {
+		return nil, err
+	}
+
+	setRobotAccountStatus(harborRobotAccount, existing.ID, existing.Name, existing.ExpiresAt)
+	if refreshAt := robotAccountRefreshTime(harborRobotAccount); !refreshAt.IsZero() && !time.Now().Before(refreshAt) {
+		// Harbor cannot extend the lifetime of a robot account, so it is
+		// replaced by a new one with fresh credentials.
+		err = client.DeleteRobotAccountByID(ctx, existing.ID)
+		if err != nil {
+			return nil, err
+		}
+		err = r.createRobotAccount(ctx, harborRobotAccount, requested, registry, client)
+		if err != nil {
+			return nil, err
+		}
+		owner.Events.credentialsRefreshed(harborRobotAccount.Status.RobotName, harborRobotAccount.Status.ID)
+		return nil, nil
+	}
+
+	drifted := robotAccountDrift(existing, requested)
+	if len(drifted) > 0 || currentOwner != owner.Owner {
+		err = client.UpdateRobotAccount(ctx, &modelv2.Robot{
+			ID:          existing.ID,
+			Name:        existing.Name,
+			Level:       existing.Level,
+			Editable:    existing.Editable,
+			Description: requested.Description,
+			Disable:     requested.Disable,
+			Duration:    requested.Duration,
+			Permissions: requested.Permissions,
+		})
+		if err != nil {
+			return nil, err
+		}
+		// A new duration moves the expiry.
+		updated, err := client.GetRobotAccountByID(ctx, existing.ID)
+		if err != nil {
+			return nil, err
+		}
+		setRobotAccountStatus(harborRobotAccount, updated.ID, updated.Name, updated.ExpiresAt)
+	}
+
+	// Harbor never returns the secret of a robot account, so a new one is
+	// generated when the Secret lost it.
+	username, password, err := r.storedCredentials(ctx, harborRobotAccount)
+	if err != nil {
+		return nil, err
+	}
+	if username != existing.Name || password == "" {
+		sec, err := client.RefreshRobotAccountSecretByID(ctx, existing.ID, "")
+		if err != nil {
+			return nil, err
+		}
+		password = sec.Secret
+		owner.Events.credentialsRefreshed(existing.Name, existing.ID)
+	}
+	return drifted, r.writeCredentialsSecret(ctx, harborRobotAccount, registry, existing.Name, password)
+}
+
+// createRobotAccount creates the robot account and writes its credentials to
+// the Secret, which is the only chance to learn its secret.
+func (r *HarborRobotAccountReconciler) createRobotAccount(ctx context.Context, harborRobotAccount *harborconfigurationv1alpha1.HarborRobotAccount, requested *modelv2.RobotCreate, registry string, client *harborClient) error {
+	created, err := client.NewRobotAccount(ctx, requested)
+	if err != nil {
+		return err
+	}
+	setRobotAccountStatus(harborRobotAccount, created.ID, created.Name, created.ExpiresAt)
+	return r.writeCredentialsSecret(ctx, harborRobotAccount, registry, created.Name, created.Secret)
+}
+
+// storedCredentials returns the credentials found in the Secret of the robot
+// account, or empty ones when the Secret is missing or unusable.
+func (r *HarborRobotAccountReconciler) storedCredentials(ctx context.Context, harborRobotAccount *harborconfigurationv1alpha1.HarborRobotAccount) (string, string, error) {
+	var secret corev1.Secret
+	err := r.APIReader.Get(ctx, types.NamespacedName{Namespace: harborRobotAccount.Namespace, Name: harborRobotAccount.CredentialsSecretName()}, &secret)
+	if apierrors.IsNotFound(err) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if !v1.IsControlledBy(&secret, harborRobotAccount) {
+		return "", "", nil
+	}
+
+	var config dockerConfigJSON
+	if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config); err != nil {
+		return "", "", nil
+	}
+	for _, auth := range config.Auths {
+		return auth.Username, auth.Password, nil
+	}
+	return "", "", nil
+}
+
+// writeCredentialsSecret writes the credentials of the robot account to its
+// Secret, refusing to take over a Secret the resource did not create.
+func (r *HarborRobotAccountReconciler) writeCredentialsSecret(ctx context.Context, harborRobotAccount *harborconfigurationv1alpha1.HarborRobotAccount, registry, username, password string) error {
+	config, err := json.Marshal(dockerConfigJSON{
+		Auths: map[string]dockerConfigAuth{
+			registry: {
+				Username: username,
+				Password: password,
+				Auth:     base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{
+			Namespace: harborRobotAccount.Namespace,
+			Name:      harborRobotAccount.CredentialsSecretName(),
+		},
+	}
+	err = r.createOrUpdateSecret(ctx, secret, func() error {
+		if !secret.CreationTimestamp.IsZero() && !v1.IsControlledBy(secret, harborRobotAccount) {
+			return fmt.Errorf("secret %s/%s already exists and is not managed by the HarborRobotAccount", secret.Namespace, secret.Name)
+		}
+		secret.Type = corev1.SecretTypeDockerConfigJson
+		secret.Data = map[string][]byte{corev1.DockerConfigJsonKey: config}
+		return controllerutil.SetControllerReference(harborRobotAccount, secret, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
+	harborRobotAccount.Status.SecretName = secret.Name
+	return nil
+}
+
+// deleteRobotAccount deletes the robot account unless it is owned by another
+// resource or by no resource at all.
+func deleteRobotAccount(ctx context.Context, robot *modelv2.Robot, owner ownership, client *harborClient) error {
+	if !owner.owns(robot.ID, describedOwner(robot.Description)) {
+		return nil
+	}
+	err := client.DeleteRobotAccountByID(ctx, robot.ID)
+	if err != nil {
+		owner.Events.deleteFailed("robot account", robot.Name, robot.ID, err)
+		return err
+	}
+	owner.Events.deleted("robot account", robot.Name, robot.ID)
+	return nil
+}
+
+// releaseRobotAccount removes the ownership marker of a robot account the
+// resource owns, leaving the robot account in Harbor.
+func releaseRobotAccount(ctx context.Context, robot *modelv2.Robot, owner ownership, client *harborClient) error {
+	currentOwner := describedOwner(robot.Description)
+	if !owner.owns(robot.ID, currentOwner) {
+		return nil
+	}
+	if currentOwner != "" {
+		robot.Description = stripOwner(robot.Description)
+		if err := client.UpdateRobotAccount(ctx, robot); err != nil {
+			return err
+		}
+	}
+	owner.Events.orphaned("robot account", robot.Name, robot.ID)
+	return nil
+}
+
+// robotAccountName returns the name the robot account is looked up by in
+// Harbor, which carries the project for project level accounts.
+func robotAccountName(harborRobotAccount *harborconfigurationv1alpha1.HarborRobotAccount) string {
+	name := harborRobotAccount.RobotAccountName()
+	if harborRobotAccount.Spec.Level != harborconfigurationv1alpha1.RobotAccountLevelSystem {
+		name = harborRobotAccount.Spec.ProjectName + "+" + name
+	}
+	return name
+}
+
+// robotAccountRequest builds the Harbor robot account from the spec.
+func robotAccountRequest(harborRobotAccount *harborconfigurationv1alpha1.HarborRobotAccount, owner string) (*modelv2.RobotCreate, error) {
+	spec := harborRobotAccount.Spec
+	level := spec.Level
+	if level == "" {
+		level = harborconfigurationv1alpha1.RobotAccountLevelProject
+	}
+	if level == harborconfigurationv1alpha1.RobotAccountLevelProject && spec.ProjectName == "" {
+		return nil, errors.New("projectName is required for project level robot accounts")
+	}
+
+	permissions := make([]*modelv2.RobotPermission, 0, len(spec.Permissions))
+	for _, permission := range spec.Permissions {
+		kind := permission.Kind
+		if kind == "" {
+			kind = "project"
+		}
+		namespace := permission.Namespace
+		if namespace == "" {
+			namespace = spec.ProjectName
+		}
+		if namespace == "" {
+			return nil, errors.New("permissions of system level robot accounts need a namespace")
+		}
+
+		access := make([]*modelv2.Access, 0, len(permission.Access))
+		for _, item := range permission.Access {
+			effect := item.Effect
+			if effect == "" {
+				effect = "allow"
+			}
+			access = append(access, &modelv2.Access{
+				Resource: item.Resource,
+				Action:   item.Action,
+				Effect:   effect,
+			})
+		}
+		permissions = append(permissions, &modelv2.RobotPermission{
+			Kind:      kind,
+			Namespace: namespace,
+			Access:    access,
+		})
+	}
+
+	duration := spec.Duration
+	if duration == 0 {
+		duration = 30
+	}
+	return &modelv2.RobotCreate{
+		Name:        harborRobotAccount.RobotAccountName(),
+		Description: withOwner(spec.Description, owner),
+		Level:       string(level),
+		Disable:     spec.Disabled,
+		Duration:    duration,
+		Permissions: permissions,
+	}, nil
+}
+
+// robotAccountForbiddenError is returned when the spec asks for permissions
+// the robot account may not be granted from its namespace.
+type robotAccountForbiddenError struct {
+	reason string
+}
+
+func (e *robotAccountForbiddenError) Error() string {
+	return e.reason
+}
+
+// checkRobotAccountScope makes sure a robot account only gets permissions on
+// projects managed from its own namespace, so that a HarborRobotAccount
+// cannot be used to read the images of other tenants. System level robot
+// accounts are only allowed when the operator was started with
+// --allow-system-robot-accounts.
+func (r *HarborRobotAccountReconciler) checkRobotAccountScope(ctx context.Context, harborRobotAccount *harborconfigurationv1alpha1.HarborRobotAccount, requested *modelv2.RobotCreate, client *harborClient) error {
+	if requested.Level == string(harborconfigurationv1alpha1.RobotAccountLevelSystem) {
+		if !r.AllowSystemRobotAccounts {
+			return &robotAccountForbiddenError{reason: "system level robot accounts are not allowed by the operator"}
+		}
+		return nil
+	}
+
+	projects := []string{harborRobotAccount.Spec.ProjectName}
+	for _, permission := range requested.Permissions {
+		if permission.Kind == "project" {
+			projects = append(projects, permission.Namespace)
+		}
+	}
+
+	checked := map[string]bool{}
+	for _, name := range projects {
+		if checked[name] {
+			continue
+		}
+		checked[name] = true
+
+		project, err := client.GetProject(ctx, name)
+		if err != nil {
+			return err
+		}
+		owner, err := projectOwner(ctx, project.ProjectID, client)
+		if err != nil {
+			return err
+		}
+		if ownerNamespace(owner) != harborRobotAccount.Namespace {
+			return &robotAccountForbiddenError{reason: fmt.Sprintf("project %q is not managed by a resource in namespace %q", name, harborRobotAccount.Namespace)}
+		}
+	}
+	return nil
+}
+
+// setRobotAccountStatus records the robot account in the status.
+func setRobotAccountStatus(harborRobotAccount *harborconfigurationv1alpha1.HarborRobotAccount, id int64, name string, expiresAt int64) {
+	harborRobotAccount.Status.ID = id
+	harborRobotAccount.Status.RobotName = name
+	harborRobotAccount.Status.ExpiresAt = nil
+	if expiresAt > 0 {
+		expires := v1.Unix(expiresAt, 0)
+		harborRobotAccount.Status.ExpiresAt = &expires
+	}
+}
+
+// robotAccountRefreshTime returns when the credentials recorded in the status
+// are replaced, or the zero time when they never expire. refreshBefore is
+// capped at half the lifetime of the credentials, so that they are not
+// replaced right after they were created.
+func robotAccountRefreshTime(harborRobotAccount *harborconfigurationv1alpha1.HarborRobotAccount) time.Time {
+	if harborRobotAccount.Status.ExpiresAt == nil {
+		return time.Time{}
+	}
+	refreshBefore := defaultRobotAccountRefreshBefore
+	if harborRobotAccount.Spec.RefreshBefore != nil {
+		refreshBefore = harborRobotAccount.Spec.RefreshBefore.Duration
+	}
+	if lifetime := time.Duration(harborRobotAccount.Spec.Duration) * 24 * time.Hour; lifetime > 0 && refreshBefore > lifetime/2 {
+		refreshBefore = lifetime / 2
+	}
+	return harborRobotAccount.Status.ExpiresAt.Add(-refreshBefore)
+}
+
+// harborRobotAccountResource returns the status of the HarborRobotAccount.
+func harborRobotAccountResource(harborRobotAccount *harborconfigurationv1alpha1.HarborRobotAccount) harborResource {
+	return harborResource{
+		object:             harborRobotAccount,
+		conditions:         &harborRobotAccount.Status.Conditions,
+		observedGeneration: &harborRobotAccount.Status.ObservedGeneration,
+		kind:               "robot",
+		managed:            func() bool { return harborRobotAccount.Status.ID != 0 },
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HarborRobotAccountReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&harborconfigurationv1alpha1.HarborRobotAccount{}).
+		Owns(&corev1.Secret{}, builder.OnlyMetadata).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.findRobotAccountForSecretCopy), builder.OnlyMetadata).
+		Watches(&source.Kind{Type: &corev1.Namespace{}}, handler.EnqueueRequestsFromMapFunc(r.findRobotAccountsForNamespace), builder.OnlyMetadata).
+		Complete(r)
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	modelv2 "github.com/mittwald/goharbor-client/v5/apiv2/model"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+func TestRobotAccountRefreshTime(t *testing.T) {
+	expiresAt := time.Date(2022, 6, 30, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		expiresAt     *v1.Time
+		duration      int64
+		refreshBefore *v1.Duration
+		want          time.Time
+	}{
+		{
+			name:     "no expiry",
+			duration: 30,
+		},
+		{
+			name:      "default refresh before",
+			expiresAt: &v1.Time{Time: expiresAt},
+			duration:  30,
+			want:      expiresAt.Add(-24 * time.Hour),
+		},
+		{
+			name:          "refresh before from the spec",
+			expiresAt:     &v1.Time{Time: expiresAt},
+			duration:      30,
+			refreshBefore: &v1.Duration{Duration: 72 * time.Hour},
+			want:          expiresAt.Add(-72 * time.Hour),
+		},
+		{
+			name:          "refresh before capped at half the lifetime",
+			expiresAt:     &v1.Time{Time: expiresAt},
+			duration:      2,
+			refreshBefore: &v1.Duration{Duration: 72 * time.Hour},
+			want:          expiresAt.Add(-24 * time.Hour),
+		},
+		{
+			name:          "no lifetime cap without a duration",
+			expiresAt:     &v1.Time{Time: expiresAt},
+			refreshBefore: &v1.Duration{Duration: 72 * time.Hour},
+			want:          expiresAt.Add(-72 * time.Hour),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			harborRobotAccount := &harborconfigurationv1alpha1.HarborRobotAccount{
+				Spec: harborconfigurationv1alpha1.HarborRobotAccountSpec{
+					Duration:      tt.duration,
+					RefreshBefore: tt.refreshBefore,
+				},
+				Status: harborconfigurationv1alpha1.HarborRobotAccountStatus{ExpiresAt: tt.expiresAt},
+			}
+			if got := robotAccountRefreshTime(harborRobotAccount); !got.Equal(tt.want) {
+				t.Errorf("robotAccountRefreshTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeProjectOwners serves projects labelled with the owner at the same
+// index, their IDs being the index plus one.
+type fakeProjectOwners []struct{ name, owner string }
+
+func (f fakeProjectOwners) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v2.0")
+	for i, project := range f {
+		id := strconv.Itoa(i + 1)
+		switch {
+		case r.Method == http.MethodGet && path == "/projects/"+project.name:
+			writeJSON(w, modelv2.Project{Name: project.name, ProjectID: int32(i + 1)})
+			return
+		case r.Method == http.MethodGet && path == "/labels" && r.URL.Query().Get("project_id") == id:
+			var labels []*modelv2.Label
+			if project.owner != "" && r.URL.Query().Get("page") == "1" {
+				labels = append(labels, &modelv2.Label{Name: managedByLabel, Description: project.owner})
+			}
+			w.Header().Set("X-Total-Count", strconv.Itoa(len(labels)))
+			writeJSON(w, labels)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+func TestCheckRobotAccountScope(t *testing.T) {
+	projects := fakeProjectOwners{
+		{name: "team-a", owner: "HarborProject team-a/images"},
+		{name: "shared", owner: "HarborConfiguration team-a/harbor"},
+		{name: "team-b", owner: "HarborProject team-b/images"},
+		{name: "manual"},
+	}
+	client := newFakeHarborClient(t, projects)
+
+	tests := []struct {
+		name        string
+		level       harborconfigurationv1alpha1.RobotAccountLevel
+		projectName string
+		permissions []harborconfigurationv1alpha1.RobotPermission
+		allowSystem bool
+		wantErr     bool
+		forbidden   bool
+	}{
+		{
+			name:        "project owned from the namespace",
+			projectName: "team-a",
+			permissions: []harborconfigurationv1alpha1.RobotPermission{{}},
+		},
+		{
+			name:        "permissions on another project owned from the namespace",
+			projectName: "team-a",
+			permissions: []harborconfigurationv1alpha1.RobotPermission{{}, {Namespace: "shared"}},
+		},
+		{
+			name:        "project owned from another namespace",
+			projectName: "team-b",
+			permissions: []harborconfigurationv1alpha1.RobotPermission{{}},
+			wantErr:     true,
+			forbidden:   true,
+		},
+		{
+			name:        "permissions on a project owned from another namespace",
+			projectName: "team-a",
+			permissions: []harborconfigurationv1alpha1.RobotPermission{{}, {Namespace: "team-b"}},
+			wantErr:     true,
+			forbidden:   true,
+		},
+		{
+			name:        "project not managed by the operator",
+			projectName: "manual",
+			permissions: []harborconfigurationv1alpha1.RobotPermission{{}},
+			wantErr:     true,
+			forbidden:   true,
+		},
+		{
+			name:        "missing project",
+			projectName: "missing",
+			permissions: []harborconfigurationv1alpha1.RobotPermission{{}},
+			wantErr:     true,
+		},
+		{
+			name:        "system level not allowed",
+			level:       harborconfigurationv1alpha1.RobotAccountLevelSystem,
+			permissions: []harborconfigurationv1alpha1.RobotPermission{{Namespace: "team-a"}},
+			wantErr:     true,
+			forbidden:   true,
+		},
+		{
+			name:        "system level allowed",
+			level:       harborconfigurationv1alpha1.RobotAccountLevelSystem,
+			permissions: []harborconfigurationv1alpha1.RobotPermission{{Namespace: "team-b"}},
+			allowSystem: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			harborRobotAccount := &harborconfigurationv1alpha1.HarborRobotAccount{
+				ObjectMeta: v1.ObjectMeta{Namespace: "team-a", Name: "pull"},
+				Spec: harborconfigurationv1alpha1.HarborRobotAccountSpec{
+					Level:       tt.level,
+					ProjectName: tt.projectName,
+					Permissions: tt.permissions,
+				},
+			}
+			requested, err := robotAccountRequest(harborRobotAccount, "HarborRobotAccount team-a/pull")
+			if err != nil {
+				t.Fatalf("robotAccountRequest() error = %v", err)
+			}
+
+			r := &HarborRobotAccountReconciler{AllowSystemRobotAccounts: tt.allowSystem}
+			err = r.checkRobotAccountScope(context.Background(), harborRobotAccount, requested, client)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkRobotAccountScope() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var forbidden *robotAccountForbiddenError
+			if errors.As(err, &forbidden) != tt.forbidden {
+				t.Errorf("checkRobotAccountScope() error = %v, forbidden %v", err, tt.forbidden)
+			}
+		})
+	}
+}
+
+func TestOwnerNamespace(t *testing.T) {
+	tests := []struct {
+		owner string
+		want  string
+	}{
+		{owner: "HarborProject team-a/images", want: "team-a"},
+		{owner: "HarborConfiguration default/harbor", want: "default"},
+		{owner: ""},
+		{owner: "someone"},
+		{owner: "HarborProject images"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.owner, func(t *testing.T) {
+			if got := ownerNamespace(tt.owner); got != tt.want {
+				t.Errorf("ownerNamespace(%q) = %q, want %q", tt.owner, got, tt.want)
+			}
+		})
+	}
+}
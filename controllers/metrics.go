@@ -0,0 +1,157 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+const metricsNamespace = "harbor_config_operator"
+
+var (
+	harborRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "harbor_api_requests_total",
+		Help:      "Number of Harbor API requests by operation, target and status code.",
+	}, []string{"operation", "target", "code"})
+
+	harborRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "harbor_api_request_duration_seconds",
+		Help:      "Latency of Harbor API requests by operation and target.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "target"})
+
+	harborRequestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "harbor_api_errors_total",
+		Help:      "Number of failed Harbor API requests by operation and target. Not found responses are not counted.",
+	}, []string{"operation", "target"})
+
+	managedObjects = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "managed_objects",
+		Help:      "Number of Harbor objects managed by a resource, by kind of Harbor object.",
+	}, []string{"resource", "namespace", "name", "kind"})
+
+	replicationExecutions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "replication_executions_total",
+		Help:      "Number of finished replication executions by replication rule and status.",
+	}, []string{"policy", "status"})
+
+	driftCorrections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "drift_corrections_total",
+		Help:      "Number of Harbor objects whose drift from the spec was corrected, by kind of resource.",
+	}, []string{"resource"})
+
+	resourceReady = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "resource_ready",
+		Help:      "Whether a resource is Ready (1) or not (0).",
+	}, []string{"resource", "namespace", "name"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		harborRequests,
+		harborRequestDuration,
+		harborRequestErrors,
+		managedObjects,
+		replicationExecutions,
+		driftCorrections,
+		resourceReady,
+	)
+}
+
+// observeHarborRequest records a request to the Harbor API.
+func observeHarborRequest(req *http.Request, resp *http.Response, err error, duration time.Duration) {
+	operation := req.Method + " " + harborOperation(req.URL.Path)
+	target := req.URL.Host
+
+	code := "error"
+	if resp != nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	harborRequests.WithLabelValues(operation, target, code).Inc()
+	harborRequestDuration.WithLabelValues(operation, target).Observe(duration.Seconds())
+	// Lookups of missing objects are expected, e.g. before creating them.
+	if err != nil || (resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound) {
+		harborRequestErrors.WithLabelValues(operation, target).Inc()
+	}
+}
+
+// harborOperation turns the path of a Harbor API request into a template that
+// keeps the number of label values low, e.g. /api/v2.0/projects/library/members/3
+// becomes /projects/{id}/members/{id}.
+func harborOperation(path string) string {
+	if i := strings.Index(path, "/v2.0"); i >= 0 {
+		path = path[i+len("/v2.0"):]
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		_, err := strconv.ParseInt(segment, 10, 64)
+		if err == nil || (i > 0 && segments[i-1] == "projects") {
+			segments[i] = "{id}"
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// recordResourceStatus updates the ready gauge of a resource and the number
+// of Harbor objects it manages by kind.
+func recordResourceStatus(object client.Object, conditions []v1.Condition, managed map[string]int) {
+	resource := resourceKind(object)
+	ready := 0.0
+	if meta.IsStatusConditionTrue(conditions, harborconfigurationv1alpha1.ConditionReady) {
+		ready = 1
+	}
+	resourceReady.WithLabelValues(resource, object.GetNamespace(), object.GetName()).Set(ready)
+	for kind, count := range managed {
+		managedObjects.WithLabelValues(resource, object.GetNamespace(), object.GetName(), kind).Set(float64(count))
+	}
+}
+
+// forgetResource removes the gauges of a deleted resource.
+func forgetResource(object client.Object) {
+	labels := prometheus.Labels{
+		"resource":  resourceKind(object),
+		"namespace": object.GetNamespace(),
+		"name":      object.GetName(),
+	}
+	resourceReady.Delete(labels)
+	managedObjects.DeletePartialMatch(labels)
+}
+
+// resourceKind returns the kind of a resource, which is not always set in
+// the TypeMeta of objects read through the client.
+func resourceKind(object client.Object) string {
+	return reflect.TypeOf(object).Elem().Name()
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+func TestHarborOperation(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/api/v2.0/projects", want: "/projects"},
+		{path: "/api/v2.0/projects/library", want: "/projects/{id}"},
+		{path: "/api/v2.0/projects/library/members/3", want: "/projects/{id}/members/{id}"},
+		{path: "/api/v2.0/registries/12", want: "/registries/{id}"},
+		{path: "/api/v2.0/replication/executions", want: "/replication/executions"},
+		{path: "/harbor/api/v2.0/robots/7", want: "/robots/{id}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := harborOperation(tt.path); got != tt.want {
+				t.Errorf("harborOperation() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObserveHarborRequest(t *testing.T) {
+	harbor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2.0/projects/library":
+			w.WriteHeader(http.StatusOK)
+		case "/api/v2.0/registries/3":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/api/v2.0/robots/7":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer harbor.Close()
+	// Requests to a closed server fail before any response.
+	unavailable := httptest.NewServer(http.NotFoundHandler())
+	unavailable.Close()
+
+	httpClient, err := newHarborHTTPClient(harborTLS{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		server        *httptest.Server
+		path          string
+		wantOperation string
+		wantCode      string
+		wantError     bool
+	}{
+		{
+			name:          "success",
+			server:        harbor,
+			path:          "/api/v2.0/projects/library",
+			wantOperation: "GET /projects/{id}",
+			wantCode:      "200",
+		},
+		{
+			name:          "not found",
+			server:        harbor,
+			path:          "/api/v2.0/projects/missing",
+			wantOperation: "GET /projects/{id}",
+			wantCode:      "404",
+		},
+		{
+			name:          "server error",
+			server:        harbor,
+			path:          "/api/v2.0/registries/3",
+			wantOperation: "GET /registries/{id}",
+			wantCode:      "500",
+			wantError:     true,
+		},
+		{
+			name:          "client error",
+			server:        harbor,
+			path:          "/api/v2.0/robots/7",
+			wantOperation: "GET /robots/{id}",
+			wantCode:      "401",
+			wantError:     true,
+		},
+		{
+			name:          "transport error",
+			server:        unavailable,
+			path:          "/api/v2.0/projects/library",
+			wantOperation: "GET /projects/{id}",
+			wantCode:      "error",
+			wantError:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := url.Parse(tt.server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			requests := harborRequests.WithLabelValues(tt.wantOperation, target.Host, tt.wantCode)
+			errors := harborRequestErrors.WithLabelValues(tt.wantOperation, target.Host)
+			requestsBefore, errorsBefore := testutil.ToFloat64(requests), testutil.ToFloat64(errors)
+			durationsBefore := observedDurations(t, tt.wantOperation, target.Host)
+
+			resp, err := httpClient.Get(tt.server.URL + tt.path)
+			if err == nil {
+				resp.Body.Close()
+			}
+
+			if got := testutil.ToFloat64(requests) - requestsBefore; got != 1 {
+				t.Errorf("%s requests with code %s counted %v times, want 1", tt.wantOperation, tt.wantCode, got)
+			}
+			wantErrors := 0.0
+			if tt.wantError {
+				wantErrors = 1
+			}
+			if got := testutil.ToFloat64(errors) - errorsBefore; got != wantErrors {
+				t.Errorf("%s errors counted %v times, want %v", tt.wantOperation, got, wantErrors)
+			}
+			if got := observedDurations(t, tt.wantOperation, target.Host) - durationsBefore; got != 1 {
+				t.Errorf("%s durations observed %d times, want 1", tt.wantOperation, got)
+			}
+		})
+	}
+}
+
+// observedDurations returns how many durations of requests were observed for
+// operation on target.
+func observedDurations(t *testing.T, operation, target string) uint64 {
+	t.Helper()
+	var metric dto.Metric
+	if err := harborRequestDuration.WithLabelValues(operation, target).(prometheus.Metric).Write(&metric); err != nil {
+		t.Fatal(err)
+	}
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestRecordResourceStatus(t *testing.T) {
+	project := &harborconfigurationv1alpha1.HarborProject{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "metrics"}}
+	ready := []metav1.Condition{{Type: harborconfigurationv1alpha1.ConditionReady, Status: metav1.ConditionTrue}}
+
+	recordResourceStatus(project, ready, map[string]int{"member": 2, "robot": 1})
+	if got := testutil.ToFloat64(resourceReady.WithLabelValues("HarborProject", "team-a", "metrics")); got != 1 {
+		t.Errorf("resource_ready = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(managedObjects.WithLabelValues("HarborProject", "team-a", "metrics", "member")); got != 2 {
+		t.Errorf("managed_objects of members = %v, want 2", got)
+	}
+
+	recordResourceStatus(project, nil, nil)
+	if got := testutil.ToFloat64(resourceReady.WithLabelValues("HarborProject", "team-a", "metrics")); got != 0 {
+		t.Errorf("resource_ready = %v, want 0", got)
+	}
+
+	forgetResource(project)
+	labels := prometheus.Labels{"resource": "HarborProject", "namespace": "team-a", "name": "metrics"}
+	if resourceReady.Delete(labels) {
+		t.Error("forgetResource() kept resource_ready")
+	}
+	if n := managedObjects.DeletePartialMatch(labels); n != 0 {
+		t.Errorf("forgetResource() kept %d managed_objects series", n)
+	}
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	modelv2 "github.com/mittwald/goharbor-client/v5/apiv2/model"
+	harborlabel "github.com/mittwald/goharbor-client/v5/apiv2/pkg/clients/label"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+// managedByLabel is the project label whose description names the resource
+// managing a Harbor project.
+const managedByLabel = "harbor-config-operator"
+
+// managedByMarker is appended to the description of registries and
+// replication rules to name the resource managing them.
+var managedByMarker = regexp.MustCompile(`\s*\[managed-by harbor-config-operator: ([^\]]*)\]$`)
+
+// ownership identifies the resource reconciling a Harbor object and decides
+// whether it may take over an existing object.
+type ownership struct {
+	// Owner names the resource, e.g. "HarborConfiguration default/mirrors".
+	Owner  string
+	Policy harborconfigurationv1alpha1.AdoptionPolicy
+	// KnownID is the Harbor ID recorded in the status of the resource.
+	// Objects created before ownership markers were introduced carry no
+	// marker and are recognised by it.
+	KnownID int64
+	// Legacy is set for resources reconciled before ownership markers and
+	// status IDs were introduced. The unmarked objects they declare are
+	// treated as theirs, and get marked when they are reconciled.
+	Legacy bool
+	// Events records what happens to the Harbor objects on the resource.
+	Events harborEvents
+}
+
+func newOwnership(kind string, object client.Object, policy harborconfigurationv1alpha1.AdoptionPolicy, knownID int64, recorder record.EventRecorder) ownership {
+	return ownership{
+		Owner:   fmt.Sprintf("%s %s/%s", kind, object.GetNamespace(), object.GetName()),
+		Policy:  policy,
+		KnownID: knownID,
+		Events:  harborEvents{recorder: recorder, object: object},
+	}
+}
+
+// ownershipConflictError is returned when an existing Harbor object is not
+// owned by the resource and the adoption policy does not allow taking it over.
+type ownershipConflictError struct {
+	kind  string
+	name  string
+	owner string
+}
+
+func (e *ownershipConflictError) Error() string {
+	if e.owner == "" {
+		return fmt.Sprintf("%s %q already exists in Harbor and is not managed by the operator", e.kind, e.name)
+	}
+	return fmt.Sprintf("%s %q already exists in Harbor and is managed by %s", e.kind, e.name, e.owner)
+}
+
+// owns reports whether the existing object with the given ID and owner
+// belongs to the resource.
+func (o ownership) owns(id int64, currentOwner string) bool {
+	if currentOwner == "" {
+		return o.Legacy || (o.KnownID != 0 && id == o.KnownID)
+	}
+	return currentOwner == o.Owner
+}
+
+// claim returns an ownershipConflictError unless the resource owns the
+// existing object or its adoption policy allows taking it over.
+func (o ownership) claim(kind, name string, id int64, currentOwner string) error {
+	switch {
+	case o.owns(id, currentOwner):
+		return nil
+	case o.Policy == harborconfigurationv1alpha1.AdoptionPolicyAlways:
+	case o.Policy == harborconfigurationv1alpha1.AdoptionPolicyIfUnowned && currentOwner == "":
+	default:
+		return &ownershipConflictError{kind: kind, name: name, owner: currentOwner}
+	}
+	o.Events.adopted(kind, name, id, currentOwner)
+	return nil
+}
+
+// reportConflicts sets the Conflict condition from the ownership conflicts
+// among the reconciliation errors.
+func reportConflicts(object client.Object, conditions *[]v1.Condition, errs []error) {
+	var conflicts []string
+	for _, err := range errs {
+		var conflict *ownershipConflictError
+		if errors.As(err, &conflict) {
+			conflicts = append(conflicts, conflict.Error())
+		}
+	}
+
+	if len(conflicts) == 0 {
+		setStatusCondition(conditions, object.GetGeneration(), harborconfigurationv1alpha1.ConditionConflict, v1.ConditionFalse, harborconfigurationv1alpha1.ReasonNoConflict, "")
+		return
+	}
+	setStatusCondition(conditions, object.GetGeneration(), harborconfigurationv1alpha1.ConditionConflict, v1.ConditionTrue, harborconfigurationv1alpha1.ReasonOwnershipConflict, strings.Join(conflicts, "; "))
+}
+
+// describedOwner returns the owner named in the description, if any.
+func describedOwner(description string) string {
+	match := managedByMarker.FindStringSubmatch(description)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// stripOwner removes the ownership marker from the description.
+func stripOwner(description string) string {
+	return managedByMarker.ReplaceAllString(description, "")
+}
+
+// withOwner returns the description marked as managed by owner.
+func withOwner(description, owner string) string {
+	marker := fmt.Sprintf("[managed-by harbor-config-operator: %s]", owner)
+	description = stripOwner(description)
+	if description == "" {
+		return marker
+	}
+	return description + " " + marker
+}
+
+// projectOwnerLabel returns the label marking the owner of the project, or
+// nil when the project carries none.
+func projectOwnerLabel(ctx context.Context, projectID int32, client *harborClient) (*modelv2.Label, error) {
+	id := int64(projectID)
+	labels, err := client.ListLabels(ctx, managedByLabel, &id, harborlabel.ScopeProject)
+	if err != nil {
+		return nil, err
+	}
+	for _, label := range labels {
+		// The name filter of Harbor also matches labels containing the name.
+		if label.Name == managedByLabel {
+			return label, nil
+		}
+	}
+	return nil, nil
+}
+
+// projectOwner returns the owner named by the label of the project, if any.
+func projectOwner(ctx context.Context, projectID int32, client *harborClient) (string, error) {
+	label, err := projectOwnerLabel(ctx, projectID, client)
+	if err != nil || label == nil {
+		return "", err
+	}
+	return strings.TrimSpace(label.Description), nil
+}
+
+// ownerNamespace returns the namespace of the resource named by owner, e.g.
+// "team-a" for "HarborProject team-a/images".
+func ownerNamespace(owner string) string {
+	_, resource, found := strings.Cut(owner, " ")
+	if !found {
+		return ""
+	}
+	namespace, _, found := strings.Cut(resource, "/")
+	if !found {
+		return ""
+	}
+	return namespace
+}
+
+// setProjectOwner labels the project as managed by owner.
+func setProjectOwner(ctx context.Context, projectID int32, owner string, client *harborClient) error {
+	label, err := projectOwnerLabel(ctx, projectID, client)
+	if err != nil {
+		return err
+	}
+	if label == nil {
+		return client.CreateLabel(ctx, &modelv2.Label{
+			Name:        managedByLabel,
+			Description: owner,
+			Scope:       harborlabel.ScopeProject.String(),
+			ProjectID:   int64(projectID),
+		})
+	}
+	if label.Description == owner {
+		return nil
+	}
+	label.Description = owner
+	return client.UpdateLabel(ctx, label.ID, label)
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/record"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+func TestOwnershipClaim(t *testing.T) {
+	const self = "HarborConfiguration default/mirrors"
+	const other = "HarborProject team/images"
+
+	tests := []struct {
+		name         string
+		policy       harborconfigurationv1alpha1.AdoptionPolicy
+		knownID      int64
+		legacy       bool
+		id           int64
+		currentOwner string
+		wantOwns     bool
+		wantConflict bool
+		wantAdopted  bool
+	}{
+		{
+			name:         "marked as own",
+			id:           1,
+			currentOwner: self,
+			wantOwns:     true,
+		},
+		{
+			name:     "unmarked with the known ID",
+			knownID:  1,
+			id:       1,
+			wantOwns: true,
+		},
+		{
+			name:         "unmarked with another ID",
+			knownID:      1,
+			id:           2,
+			wantConflict: true,
+		},
+		{
+			name:         "unmarked without a known ID",
+			id:           1,
+			wantConflict: true,
+		},
+		{
+			name:     "unmarked for a legacy resource",
+			legacy:   true,
+			id:       1,
+			wantOwns: true,
+		},
+		{
+			name:         "marked by another resource for a legacy resource",
+			legacy:       true,
+			id:           1,
+			currentOwner: other,
+			wantConflict: true,
+		},
+		{
+			name:         "marked by another resource with the known ID",
+			knownID:      1,
+			id:           1,
+			currentOwner: other,
+			wantConflict: true,
+		},
+		{
+			name:        "unmarked if unowned",
+			policy:      harborconfigurationv1alpha1.AdoptionPolicyIfUnowned,
+			id:          1,
+			wantAdopted: true,
+		},
+		{
+			name:         "marked by another resource if unowned",
+			policy:       harborconfigurationv1alpha1.AdoptionPolicyIfUnowned,
+			id:           1,
+			currentOwner: other,
+			wantConflict: true,
+		},
+		{
+			name:         "marked by another resource always",
+			policy:       harborconfigurationv1alpha1.AdoptionPolicyAlways,
+			id:           1,
+			currentOwner: other,
+			wantAdopted:  true,
+		},
+		{
+			name:         "never",
+			policy:       harborconfigurationv1alpha1.AdoptionPolicyNever,
+			id:           1,
+			wantConflict: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			owner := ownership{
+				Owner:   self,
+				Policy:  tt.policy,
+				KnownID: tt.knownID,
+				Legacy:  tt.legacy,
+				Events:  harborEvents{recorder: recorder, object: &harborconfigurationv1alpha1.HarborConfiguration{}},
+			}
+
+			if got := owner.owns(tt.id, tt.currentOwner); got != tt.wantOwns {
+				t.Errorf("owns() = %v, want %v", got, tt.wantOwns)
+			}
+
+			err := owner.claim("registry", "docker-hub", tt.id, tt.currentOwner)
+			var conflict *ownershipConflictError
+			if errors.As(err, &conflict) != tt.wantConflict || (err != nil && conflict == nil) {
+				t.Errorf("claim() error = %v, want conflict %v", err, tt.wantConflict)
+			}
+
+			var event string
+			select {
+			case event = <-recorder.Events:
+			default:
+			}
+			if adopted := strings.Contains(event, harborconfigurationv1alpha1.ReasonAdopted); adopted != tt.wantAdopted {
+				t.Errorf("claim() event = %q, want adopted %v", event, tt.wantAdopted)
+			}
+		})
+	}
+}
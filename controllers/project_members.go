@@ -0,0 +1,197 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	modelv2 "github.com/mittwald/goharbor-client/v5/apiv2/model"
+	"github.com/mittwald/goharbor-client/v5/apiv2/pkg/clients/member"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+// projectMemberRoleIDs are the IDs of the project member roles in Harbor.
+var projectMemberRoleIDs = map[harborconfigurationv1alpha1.ProjectMemberRole]int64{
+	harborconfigurationv1alpha1.ProjectMemberRoleProjectAdmin: 1,
+	harborconfigurationv1alpha1.ProjectMemberRoleDeveloper:    2,
+	harborconfigurationv1alpha1.ProjectMemberRoleGuest:        3,
+	harborconfigurationv1alpha1.ProjectMemberRoleMaintainer:   4,
+	harborconfigurationv1alpha1.ProjectMemberRoleLimitedGuest: 5,
+}
+
+// Types of user groups in Harbor.
+const (
+	ldapGroupType int64 = 1
+	oidcGroupType int64 = 3
+)
+
+// projectMemberKey identifies a project member. Groups are told apart by type
+// as well, since an LDAP and an OIDC group may share a name.
+type projectMemberKey struct {
+	entityType string
+	groupType  int64
+	name       string
+}
+
+// projectMembersReconciliation adds, updates and removes the members of the
+// project to match the spec, returning the members that changed. Members are
+// left alone when the spec lists none.
+func projectMembersReconciliation(ctx context.Context, projectName string, members []harborconfigurationv1alpha1.ProjectMember, client *harborClient) ([]string, error) {
+	if members == nil {
+		return nil, nil
+	}
+
+	existingMembers, err := client.ListProjectMembers(ctx, projectName, "")
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[projectMemberKey]*modelv2.ProjectMemberEntity, len(existingMembers))
+	existingKeys := make([]projectMemberKey, len(existingMembers))
+	for i, existingMember := range existingMembers {
+		key := projectMemberKey{entityType: existingMember.EntityType, name: existingMember.EntityName}
+		if existingMember.EntityType == member.EntityTypeGroup.String() {
+			userGroup, err := client.getUserGroup(ctx, existingMember.EntityID)
+			if err != nil {
+				return nil, err
+			}
+			key.groupType = userGroup.GroupType
+		}
+		current[key] = existingMember
+		existingKeys[i] = key
+	}
+
+	var changed []string
+	requested := make(map[projectMemberKey]bool, len(members))
+	for _, projectMember := range members {
+		key := projectMemberKey{
+			entityType: projectMemberEntityType(projectMember.Kind).String(),
+			groupType:  projectMemberGroupType(projectMember.Kind),
+			name:       projectMember.Name,
+		}
+		requested[key] = true
+		roleID := projectMemberRoleIDs[projectMember.Role]
+
+		existingMember, ok := current[key]
+		switch {
+		case !ok:
+			err = client.AddProjectMember(ctx, projectName, newProjectMember(projectMember, roleID))
+			changed = append(changed, fieldChange("member "+projectMember.Name, "none", projectMember.Role))
+		case existingMember.RoleID != roleID:
+			err = client.setProjectMemberRole(ctx, projectName, existingMember.ID, roleID)
+			changed = append(changed, fieldChange("member "+projectMember.Name, projectMemberRole(existingMember), projectMember.Role))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var keep map[string]bool
+	for i, existingMember := range existingMembers {
+		key := existingKeys[i]
+		if requested[key] {
+			continue
+		}
+		if keep == nil {
+			keep, err = retainedProjectMembers(ctx, projectName, client)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if key.entityType == member.EntityTypeUser.String() && keep[key.name] {
+			continue
+		}
+		err = client.removeProjectMember(ctx, projectName, existingMember.ID)
+		if err != nil {
+			return nil, err
+		}
+		changed = append(changed, fieldChange("member "+existingMember.EntityName, projectMemberRole(existingMember), "none"))
+	}
+	return changed, nil
+}
+
+// retainedProjectMembers returns the users that stay members of the project
+// even when the spec does not list them: the owner of the project and the user
+// the operator authenticates as, without which the project could no longer be
+// managed.
+func retainedProjectMembers(ctx context.Context, projectName string, client *harborClient) (map[string]bool, error) {
+	currentUser, err := client.GetCurrentUserInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	project, err := client.GetProject(ctx, projectName)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]bool{currentUser.Username: true, project.OwnerName: true}, nil
+}
+
+func projectMemberEntityType(kind harborconfigurationv1alpha1.ProjectMemberKind) member.EntityType {
+	if kind == harborconfigurationv1alpha1.ProjectMemberKindLDAPGroup || kind == harborconfigurationv1alpha1.ProjectMemberKindOIDCGroup {
+		return member.EntityTypeGroup
+	}
+	return member.EntityTypeUser
+}
+
+// projectMemberGroupType returns the Harbor user group type of a member kind,
+// or 0 for users.
+func projectMemberGroupType(kind harborconfigurationv1alpha1.ProjectMemberKind) int64 {
+	switch kind {
+	case harborconfigurationv1alpha1.ProjectMemberKindLDAPGroup:
+		return ldapGroupType
+	case harborconfigurationv1alpha1.ProjectMemberKindOIDCGroup:
+		return oidcGroupType
+	}
+	return 0
+}
+
+// newProjectMember builds the Harbor project member to add from the spec.
+func newProjectMember(projectMember harborconfigurationv1alpha1.ProjectMember, roleID int64) *modelv2.ProjectMember {
+	switch projectMember.Kind {
+	case harborconfigurationv1alpha1.ProjectMemberKindLDAPGroup:
+		return &modelv2.ProjectMember{
+			RoleID: roleID,
+			MemberGroup: &modelv2.UserGroup{
+				GroupName:   projectMember.Name,
+				GroupType:   ldapGroupType,
+				LdapGroupDn: projectMember.LDAPGroupDN,
+			},
+		}
+	case harborconfigurationv1alpha1.ProjectMemberKindOIDCGroup:
+		return &modelv2.ProjectMember{
+			RoleID: roleID,
+			MemberGroup: &modelv2.UserGroup{
+				GroupName: projectMember.Name,
+				GroupType: oidcGroupType,
+			},
+		}
+	}
+	return &modelv2.ProjectMember{
+		RoleID:     roleID,
+		MemberUser: &modelv2.UserEntity{Username: projectMember.Name},
+	}
+}
+
+// projectMemberRole returns the role of an existing member as named in the spec.
+func projectMemberRole(existingMember *modelv2.ProjectMemberEntity) string {
+	for role, roleID := range projectMemberRoleIDs {
+		if roleID == existingMember.RoleID {
+			return string(role)
+		}
+	}
+	return existingMember.RoleName
+}
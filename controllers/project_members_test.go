@@ -0,0 +1,213 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	modelv2 "github.com/mittwald/goharbor-client/v5/apiv2/model"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+// fakeHarbor serves the parts of the Harbor API used to manage the members of
+// a single project.
+type fakeHarbor struct {
+	mu          sync.Mutex
+	currentUser string
+	owner       string
+	members     map[int64]*modelv2.ProjectMemberEntity
+	groupTypes  map[int64]int64
+	nextID      int64
+}
+
+func (f *fakeHarbor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/v2.0")
+	switch {
+	case r.Method == http.MethodGet && path == "/users/current":
+		writeJSON(w, modelv2.UserResp{Username: f.currentUser})
+	case r.Method == http.MethodGet && path == "/projects/test":
+		writeJSON(w, modelv2.Project{Name: "test", OwnerName: f.owner})
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/usergroups/"):
+		id, _ := strconv.ParseInt(strings.TrimPrefix(path, "/usergroups/"), 10, 64)
+		writeJSON(w, modelv2.UserGroup{ID: id, GroupType: f.groupTypes[id]})
+	case r.Method == http.MethodGet && path == "/projects/test/members":
+		var members []*modelv2.ProjectMemberEntity
+		if r.URL.Query().Get("page") == "1" {
+			for _, member := range f.members {
+				members = append(members, member)
+			}
+		}
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(f.members)))
+		writeJSON(w, members)
+	case r.Method == http.MethodPost && path == "/projects/test/members":
+		var member modelv2.ProjectMember
+		_ = json.NewDecoder(r.Body).Decode(&member)
+		f.nextID++
+		entity := &modelv2.ProjectMemberEntity{ID: f.nextID, EntityID: f.nextID, RoleID: member.RoleID}
+		if member.MemberGroup != nil {
+			entity.EntityType, entity.EntityName = "g", member.MemberGroup.GroupName
+			f.groupTypes[f.nextID] = member.MemberGroup.GroupType
+		} else {
+			entity.EntityType, entity.EntityName = "u", member.MemberUser.Username
+		}
+		f.members[f.nextID] = entity
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && strings.HasPrefix(path, "/projects/test/members/"):
+		id, _ := strconv.ParseInt(strings.TrimPrefix(path, "/projects/test/members/"), 10, 64)
+		var role modelv2.RoleRequest
+		_ = json.NewDecoder(r.Body).Decode(&role)
+		f.members[id].RoleID = role.RoleID
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/projects/test/members/"):
+		id, _ := strconv.ParseInt(strings.TrimPrefix(path, "/projects/test/members/"), 10, 64)
+		delete(f.members, id)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// memberRoles returns the role of every member, keyed by type and name.
+func (f *fakeHarbor) memberRoles() map[string]int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	roles := make(map[string]int64)
+	for id, member := range f.members {
+		key := member.EntityType + "/" + member.EntityName
+		if member.EntityType == "g" {
+			key += "/" + strconv.FormatInt(f.groupTypes[id], 10)
+		}
+		roles[key] = member.RoleID
+	}
+	return roles
+}
+
+func newFakeHarborClient(t *testing.T, handler http.Handler) *harborClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := newHarborClient(harborEndpoint{url: harborAPIURL(server.URL), username: "admin", password: "secret"})
+	if err != nil {
+		t.Fatalf("newHarborClient() error = %v", err)
+	}
+	return client
+}
+
+func TestProjectMembersReconciliation(t *testing.T) {
+	existing := []*modelv2.ProjectMemberEntity{
+		{ID: 1, EntityID: 1, EntityType: "u", EntityName: "admin", RoleID: 1},
+		{ID: 2, EntityID: 2, EntityType: "u", EntityName: "owner", RoleID: 1},
+		{ID: 3, EntityID: 3, EntityType: "u", EntityName: "alice", RoleID: 3},
+		{ID: 4, EntityID: 4, EntityType: "u", EntityName: "bob", RoleID: 2},
+		{ID: 5, EntityID: 5, EntityType: "g", EntityName: "devs", RoleID: 2},
+		{ID: 6, EntityID: 6, EntityType: "g", EntityName: "devs", RoleID: 3},
+	}
+	groupTypes := map[int64]int64{5: ldapGroupType, 6: oidcGroupType}
+
+	tests := []struct {
+		name        string
+		members     []harborconfigurationv1alpha1.ProjectMember
+		wantRoles   map[string]int64
+		wantChanged []string
+	}{
+		{
+			name: "members left alone",
+			wantRoles: map[string]int64{
+				"u/admin": 1, "u/owner": 1, "u/alice": 3, "u/bob": 2, "g/devs/1": 2, "g/devs/3": 3,
+			},
+		},
+		{
+			name: "members added, updated and removed",
+			members: []harborconfigurationv1alpha1.ProjectMember{
+				{Name: "alice", Role: harborconfigurationv1alpha1.ProjectMemberRoleMaintainer},
+				{Name: "carol", Role: harborconfigurationv1alpha1.ProjectMemberRoleGuest},
+				{Name: "devs", Kind: harborconfigurationv1alpha1.ProjectMemberKindOIDCGroup, Role: harborconfigurationv1alpha1.ProjectMemberRoleDeveloper},
+			},
+			wantRoles: map[string]int64{
+				"u/admin": 1, "u/owner": 1, "u/alice": 4, "u/carol": 3, "g/devs/3": 2,
+			},
+			wantChanged: []string{
+				"member alice (guest -> maintainer)",
+				"member bob (developer -> none)",
+				"member carol (none -> guest)",
+				"member devs (developer -> none)",
+				"member devs (guest -> developer)",
+			},
+		},
+		{
+			name:    "all members removed",
+			members: []harborconfigurationv1alpha1.ProjectMember{},
+			wantRoles: map[string]int64{
+				"u/admin": 1, "u/owner": 1,
+			},
+			wantChanged: []string{
+				"member alice (guest -> none)",
+				"member bob (developer -> none)",
+				"member devs (developer -> none)",
+				"member devs (guest -> none)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			harbor := &fakeHarbor{
+				currentUser: "admin",
+				owner:       "owner",
+				members:     make(map[int64]*modelv2.ProjectMemberEntity),
+				groupTypes:  make(map[int64]int64),
+				nextID:      100,
+			}
+			for _, member := range existing {
+				copied := *member
+				harbor.members[member.ID] = &copied
+			}
+			for id, groupType := range groupTypes {
+				harbor.groupTypes[id] = groupType
+			}
+
+			changed, err := projectMembersReconciliation(context.Background(), "test", tt.members, newFakeHarborClient(t, harbor))
+			if err != nil {
+				t.Fatalf("projectMembersReconciliation() error = %v", err)
+			}
+			sort.Strings(changed)
+			if !reflect.DeepEqual(changed, tt.wantChanged) {
+				t.Errorf("projectMembersReconciliation() changed = %q, want %q", changed, tt.wantChanged)
+			}
+			if roles := harbor.memberRoles(); !reflect.DeepEqual(roles, tt.wantRoles) {
+				t.Errorf("members = %v, want %v", roles, tt.wantRoles)
+			}
+		})
+	}
+}
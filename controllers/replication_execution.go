@@ -0,0 +1,154 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+// replicationExecutionPollInterval is how often an execution that is still in
+// progress is polled.
+const replicationExecutionPollInterval = 15 * time.Second
+
+// Execution statuses reported by the Harbor replication API.
+const (
+	replicationExecutionInProgress = "InProgress"
+	replicationExecutionSucceed    = "Succeed"
+	replicationExecutionFailed     = "Failed"
+	replicationExecutionStopped    = "Stopped"
+)
+
+// refreshReplicationExecution records the progress of the last execution
+// started by the controller. Executions whose terminal state has already been
+// recorded are not fetched again.
+func refreshReplicationExecution(ctx context.Context, policy string, runStatus *harborconfigurationv1alpha1.ReplicationRunStatus, client *harborClient) error {
+	if runStatus.LastExecutionID == 0 {
+		runStatus.LastExecution = nil
+		return nil
+	}
+	if execution := runStatus.LastExecution; execution != nil && execution.ID == runStatus.LastExecutionID && replicationExecutionDone(execution) {
+		return nil
+	}
+
+	execution, err := client.GetReplicationExecutionByID(ctx, runStatus.LastExecutionID)
+	if err != nil {
+		return fmt.Errorf("fetching execution %d: %w", runStatus.LastExecutionID, err)
+	}
+	previous := runStatus.LastExecution
+	runStatus.LastExecution = &harborconfigurationv1alpha1.ReplicationExecutionStatus{
+		ID:         execution.ID,
+		Status:     execution.Status,
+		StatusText: execution.StatusText,
+		Total:      execution.Total,
+		Succeeded:  execution.Succeed,
+		Failed:     execution.Failed,
+		InProgress: execution.InProgress,
+		Stopped:    execution.Stopped,
+		StartTime:  executionTime(execution.StartTime),
+		EndTime:    executionTime(execution.EndTime),
+	}
+	if replicationExecutionDone(runStatus.LastExecution) && (previous == nil || previous.ID != execution.ID || !replicationExecutionDone(previous)) {
+		replicationExecutions.WithLabelValues(policy, execution.Status).Inc()
+	}
+	return nil
+}
+
+// replicationExecutionDone reports whether the execution reached a terminal state.
+func replicationExecutionDone(execution *harborconfigurationv1alpha1.ReplicationExecutionStatus) bool {
+	switch execution.Status {
+	case replicationExecutionSucceed, replicationExecutionFailed, replicationExecutionStopped:
+		return true
+	}
+	return false
+}
+
+// replicationSucceededCondition summarises the last executions of the given
+// replication rules, keyed by rule name. ok is false when none of the rules
+// has been executed by the controller yet.
+func replicationSucceededCondition(runStatuses map[string]harborconfigurationv1alpha1.ReplicationRunStatus) (status v1.ConditionStatus, reason, message string, ok bool) {
+	names := make([]string, 0, len(runStatuses))
+	for name := range runStatuses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var inProgress, failed []string
+	for _, name := range names {
+		execution := runStatuses[name].LastExecution
+		if execution == nil {
+			continue
+		}
+		ok = true
+		switch {
+		case !replicationExecutionDone(execution):
+			inProgress = append(inProgress, fmt.Sprintf("replication %q: execution %d has %d of %d tasks in progress", name, execution.ID, execution.InProgress, execution.Total))
+		case execution.Status != replicationExecutionSucceed:
+			message := fmt.Sprintf("replication %q: execution %d %s with %d of %d tasks failed", name, execution.ID, strings.ToLower(execution.Status), execution.Failed, execution.Total)
+			if execution.StatusText != "" {
+				message += ": " + execution.StatusText
+			}
+			failed = append(failed, message)
+		}
+	}
+
+	switch {
+	case !ok:
+		return "", "", "", false
+	case len(failed) > 0:
+		return v1.ConditionFalse, harborconfigurationv1alpha1.ReasonReplicationFailed, strings.Join(failed, "; "), true
+	case len(inProgress) > 0:
+		return v1.ConditionUnknown, harborconfigurationv1alpha1.ReasonReplicationInProgress, strings.Join(inProgress, "; "), true
+	}
+	return v1.ConditionTrue, harborconfigurationv1alpha1.ReasonReplicationSucceeded, "", true
+}
+
+// setReplicationSucceededCondition sets the ReplicationSucceeded condition
+// from the last executions of the given replication rules, or removes it when
+// none of them has been executed by the controller yet. It returns how long
+// to wait before polling executions that are still in progress, or 0. An
+// execution still in progress is polled even when another one failed.
+func setReplicationSucceededCondition(conditions *[]v1.Condition, generation int64, runStatuses map[string]harborconfigurationv1alpha1.ReplicationRunStatus) time.Duration {
+	status, reason, message, ok := replicationSucceededCondition(runStatuses)
+	if !ok {
+		meta.RemoveStatusCondition(conditions, harborconfigurationv1alpha1.ConditionReplicationSucceeded)
+		return 0
+	}
+	setStatusCondition(conditions, generation, harborconfigurationv1alpha1.ConditionReplicationSucceeded, status, reason, message)
+	for _, runStatus := range runStatuses {
+		if runStatus.LastExecution != nil && !replicationExecutionDone(runStatus.LastExecution) {
+			return replicationExecutionPollInterval
+		}
+	}
+	return 0
+}
+
+func executionTime(t strfmt.DateTime) *v1.Time {
+	if time.Time(t).IsZero() {
+		return nil
+	}
+	return &v1.Time{Time: time.Time(t)}
+}
@@ -0,0 +1,259 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	modelv2 "github.com/mittwald/goharbor-client/v5/apiv2/model"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+func TestRefreshReplicationExecution(t *testing.T) {
+	inProgress := &harborconfigurationv1alpha1.ReplicationExecutionStatus{ID: 5, Status: replicationExecutionInProgress, Total: 4, InProgress: 4}
+	succeeded := &harborconfigurationv1alpha1.ReplicationExecutionStatus{ID: 5, Status: replicationExecutionSucceed, Total: 4, Succeeded: 4}
+	inProgressCondition := v1.Condition{
+		Type:   harborconfigurationv1alpha1.ConditionReplicationSucceeded,
+		Status: v1.ConditionUnknown,
+		Reason: harborconfigurationv1alpha1.ReasonReplicationInProgress,
+	}
+
+	tests := []struct {
+		name          string
+		runStatus     harborconfigurationv1alpha1.ReplicationRunStatus
+		conditions    []v1.Condition
+		executions    []*modelv2.ReplicationExecution
+		wantErr       bool
+		wantStatus    string
+		wantFetched   int
+		wantCondition v1.ConditionStatus
+		wantReason    string
+		wantRequeue   time.Duration
+		wantCounted   float64
+	}{
+		{
+			name: "never executed",
+		},
+		{
+			name:      "in progress",
+			runStatus: harborconfigurationv1alpha1.ReplicationRunStatus{LastExecutionID: 5},
+			executions: []*modelv2.ReplicationExecution{
+				{ID: 5, Status: replicationExecutionInProgress, Total: 4, InProgress: 2, Succeed: 2},
+			},
+			wantStatus:    replicationExecutionInProgress,
+			wantFetched:   1,
+			wantCondition: v1.ConditionUnknown,
+			wantReason:    harborconfigurationv1alpha1.ReasonReplicationInProgress,
+			wantRequeue:   replicationExecutionPollInterval,
+		},
+		{
+			name:       "in progress to succeeded",
+			runStatus:  harborconfigurationv1alpha1.ReplicationRunStatus{LastExecutionID: 5, LastExecution: inProgress},
+			conditions: []v1.Condition{inProgressCondition},
+			executions: []*modelv2.ReplicationExecution{
+				{ID: 5, Status: replicationExecutionSucceed, Total: 4, Succeed: 4},
+			},
+			wantStatus:    replicationExecutionSucceed,
+			wantFetched:   1,
+			wantCondition: v1.ConditionTrue,
+			wantReason:    harborconfigurationv1alpha1.ReasonReplicationSucceeded,
+			wantCounted:   1,
+		},
+		{
+			name:       "in progress to failed",
+			runStatus:  harborconfigurationv1alpha1.ReplicationRunStatus{LastExecutionID: 5, LastExecution: inProgress},
+			conditions: []v1.Condition{inProgressCondition},
+			executions: []*modelv2.ReplicationExecution{
+				{ID: 5, Status: replicationExecutionFailed, StatusText: "registry unreachable", Total: 4, Succeed: 3, Failed: 1},
+			},
+			wantStatus:    replicationExecutionFailed,
+			wantFetched:   1,
+			wantCondition: v1.ConditionFalse,
+			wantReason:    harborconfigurationv1alpha1.ReasonReplicationFailed,
+			wantCounted:   1,
+		},
+		{
+			name:       "in progress to stopped",
+			runStatus:  harborconfigurationv1alpha1.ReplicationRunStatus{LastExecutionID: 5, LastExecution: inProgress},
+			conditions: []v1.Condition{inProgressCondition},
+			executions: []*modelv2.ReplicationExecution{
+				{ID: 5, Status: replicationExecutionStopped, Total: 4, Succeed: 1, Stopped: 3},
+			},
+			wantStatus:    replicationExecutionStopped,
+			wantFetched:   1,
+			wantCondition: v1.ConditionFalse,
+			wantReason:    harborconfigurationv1alpha1.ReasonReplicationFailed,
+			wantCounted:   1,
+		},
+		{
+			name:          "finished execution not fetched again",
+			runStatus:     harborconfigurationv1alpha1.ReplicationRunStatus{LastExecutionID: 5, LastExecution: succeeded},
+			wantStatus:    replicationExecutionSucceed,
+			wantCondition: v1.ConditionTrue,
+			wantReason:    harborconfigurationv1alpha1.ReasonReplicationSucceeded,
+		},
+		{
+			name:      "new execution after a finished one",
+			runStatus: harborconfigurationv1alpha1.ReplicationRunStatus{LastExecutionID: 6, LastExecution: succeeded},
+			executions: []*modelv2.ReplicationExecution{
+				{ID: 6, Status: replicationExecutionInProgress, Total: 1, InProgress: 1},
+			},
+			wantStatus:    replicationExecutionInProgress,
+			wantFetched:   1,
+			wantCondition: v1.ConditionUnknown,
+			wantReason:    harborconfigurationv1alpha1.ReasonReplicationInProgress,
+			wantRequeue:   replicationExecutionPollInterval,
+		},
+		{
+			name:        "missing execution",
+			runStatus:   harborconfigurationv1alpha1.ReplicationRunStatus{LastExecutionID: 5, LastExecution: inProgress},
+			wantErr:     true,
+			wantFetched: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			harbor := &fakeReplicationExecutions{executions: tt.executions}
+			client := newFakeHarborClient(t, harbor)
+
+			runStatus := tt.runStatus
+			err := refreshReplicationExecution(context.Background(), tt.name, &runStatus, client)
+			if harbor.fetched != tt.wantFetched {
+				t.Errorf("fetched the execution %d times, want %d", harbor.fetched, tt.wantFetched)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Error("refreshReplicationExecution() succeeded for a missing execution")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("refreshReplicationExecution() error = %v", err)
+			}
+
+			var status string
+			if runStatus.LastExecution != nil {
+				status = runStatus.LastExecution.Status
+			}
+			if status != tt.wantStatus {
+				t.Errorf("LastExecution.Status = %q, want %q", status, tt.wantStatus)
+			}
+			if tt.wantStatus != "" {
+				if got := testutil.ToFloat64(replicationExecutions.WithLabelValues(tt.name, tt.wantStatus)); got != tt.wantCounted {
+					t.Errorf("replication_executions_total = %v, want %v", got, tt.wantCounted)
+				}
+			}
+
+			conditions := tt.conditions
+			requeue := setReplicationSucceededCondition(&conditions, 1, map[string]harborconfigurationv1alpha1.ReplicationRunStatus{"mirror": runStatus})
+			if requeue != tt.wantRequeue {
+				t.Errorf("requeue after %v, want %v", requeue, tt.wantRequeue)
+			}
+			condition := meta.FindStatusCondition(conditions, harborconfigurationv1alpha1.ConditionReplicationSucceeded)
+			if tt.wantCondition == "" {
+				if condition != nil {
+					t.Errorf("condition = %+v, want none", condition)
+				}
+				return
+			}
+			if condition == nil || condition.Status != tt.wantCondition || condition.Reason != tt.wantReason {
+				t.Errorf("condition = %+v, want %s with reason %s", condition, tt.wantCondition, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestSetReplicationSucceededCondition(t *testing.T) {
+	execution := func(id int64, status string) harborconfigurationv1alpha1.ReplicationRunStatus {
+		return harborconfigurationv1alpha1.ReplicationRunStatus{
+			LastExecutionID: id,
+			LastExecution:   &harborconfigurationv1alpha1.ReplicationExecutionStatus{ID: id, Status: status, Total: 1},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		runStatuses   map[string]harborconfigurationv1alpha1.ReplicationRunStatus
+		wantCondition v1.ConditionStatus
+		wantReason    string
+		wantRequeue   time.Duration
+	}{
+		{
+			name:        "none executed",
+			runStatuses: map[string]harborconfigurationv1alpha1.ReplicationRunStatus{"mirror": {}},
+		},
+		{
+			name: "all succeeded",
+			runStatuses: map[string]harborconfigurationv1alpha1.ReplicationRunStatus{
+				"mirror": execution(1, replicationExecutionSucceed),
+				"backup": execution(2, replicationExecutionSucceed),
+				"idle":   {},
+			},
+			wantCondition: v1.ConditionTrue,
+			wantReason:    harborconfigurationv1alpha1.ReasonReplicationSucceeded,
+		},
+		{
+			name: "one in progress",
+			runStatuses: map[string]harborconfigurationv1alpha1.ReplicationRunStatus{
+				"mirror": execution(1, replicationExecutionSucceed),
+				"backup": execution(2, replicationExecutionInProgress),
+			},
+			wantCondition: v1.ConditionUnknown,
+			wantReason:    harborconfigurationv1alpha1.ReasonReplicationInProgress,
+			wantRequeue:   replicationExecutionPollInterval,
+		},
+		{
+			name: "one failed while another is in progress",
+			runStatuses: map[string]harborconfigurationv1alpha1.ReplicationRunStatus{
+				"mirror": execution(1, replicationExecutionFailed),
+				"backup": execution(2, replicationExecutionInProgress),
+			},
+			wantCondition: v1.ConditionFalse,
+			wantReason:    harborconfigurationv1alpha1.ReasonReplicationFailed,
+			wantRequeue:   replicationExecutionPollInterval,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conditions := []v1.Condition{{
+				Type:   harborconfigurationv1alpha1.ConditionReplicationSucceeded,
+				Status: v1.ConditionUnknown,
+				Reason: harborconfigurationv1alpha1.ReasonReplicationInProgress,
+			}}
+			requeue := setReplicationSucceededCondition(&conditions, 1, tt.runStatuses)
+			if requeue != tt.wantRequeue {
+				t.Errorf("requeue after %v, want %v", requeue, tt.wantRequeue)
+			}
+			condition := meta.FindStatusCondition(conditions, harborconfigurationv1alpha1.ConditionReplicationSucceeded)
+			if tt.wantCondition == "" {
+				if condition != nil {
+					t.Errorf("condition = %+v, want it removed", condition)
+				}
+				return
+			}
+			if condition == nil || condition.Status != tt.wantCondition || condition.Reason != tt.wantReason {
+				t.Errorf("condition = %+v, want %s with reason %s", condition, tt.wantCondition, tt.wantReason)
+			}
+		})
+	}
+}
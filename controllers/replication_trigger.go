@@ -0,0 +1,180 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"time"
+
+	modelv2 "github.com/mittwald/goharbor-client/v5/apiv2/model"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+// replicationClockSkew is how far the start time Harbor records for an
+// execution may be from the time the controller triggered it.
+const replicationClockSkew = time.Minute
+
+// replicationTrigger converts the trigger of the spec into a Harbor trigger,
+// validating the schedule of scheduled triggers.
+func replicationTrigger(trigger *harborconfigurationv1alpha1.ReplicationTrigger) (*modelv2.ReplicationTrigger, error) {
+	if trigger == nil {
+		return nil, nil
+	}
+
+	reqTrigger := &modelv2.ReplicationTrigger{
+		Type: trigger.Type,
+	}
+	if trigger.Type != "scheduled" {
+		return reqTrigger, nil
+	}
+
+	if trigger.TriggerSettings == nil || trigger.TriggerSettings.Cron == "" {
+		return nil, errors.New("scheduled trigger requires trigger_settings.cron")
+	}
+	if _, err := harborconfigurationv1alpha1.HarborCronParser.Parse(trigger.TriggerSettings.Cron); err != nil {
+		return nil, fmt.Errorf("invalid cron %q: %w", trigger.TriggerSettings.Cron, err)
+	}
+	reqTrigger.TriggerSettings = &modelv2.ReplicationTriggerSettings{
+		Cron: trigger.TriggerSettings.Cron,
+	}
+	return reqTrigger, nil
+}
+
+// nextScheduledTime returns the first run of a scheduled trigger after now,
+// or nil when the trigger is not scheduled.
+func nextScheduledTime(trigger *harborconfigurationv1alpha1.ReplicationTrigger, now time.Time) *v1.Time {
+	if trigger == nil || trigger.Type != "scheduled" || trigger.TriggerSettings == nil {
+		return nil
+	}
+
+	schedule, err := harborconfigurationv1alpha1.HarborCronParser.Parse(trigger.TriggerSettings.Cron)
+	if err != nil {
+		return nil
+	}
+	next := v1.NewTime(schedule.Next(now.UTC()))
+	return &next
+}
+
+// runReplicationIfRequested starts an execution of the replication rule when
+// its RunRequest changed or the rule itself changed since the last execution
+// started by the controller, and records the execution in runStatus.
+func runReplicationIfRequested(ctx context.Context, replication harborconfigurationv1alpha1.Replication, replicationID int64, runStatus *harborconfigurationv1alpha1.ReplicationRunStatus, events harborEvents, client *harborClient) error {
+	newRunRequest := replication.RunRequest != "" && replication.RunRequest != runStatus.LastRunRequest
+	if !replication.EnablePolicy {
+		if newRunRequest {
+			events.replicationDisabled(replication.Name, replicationID)
+		}
+		return nil
+	}
+
+	specHash, err := replicationSpecHash(replication)
+	if err != nil {
+		return err
+	}
+	if !newRunRequest && specHash == runStatus.LastTriggeredSpecHash {
+		return nil
+	}
+
+	// An execution started by an earlier reconciliation that failed to
+	// record it in the status is still running; take it over instead of
+	// starting the replication a second time.
+	executionID, err := inFlightReplicationExecution(ctx, replicationID, runStatus.LastExecutionID, client)
+	if err != nil {
+		return err
+	}
+	if executionID == 0 {
+		executionID, err = triggerReplication(ctx, replicationID, client)
+		if err != nil {
+			events.replicationTriggerFailed(replication.Name, replicationID, err)
+			return err
+		}
+		events.replicationTriggered(replication.Name, replicationID, executionID)
+	}
+
+	runStatus.LastRunRequest = replication.RunRequest
+	runStatus.LastTriggeredSpecHash = specHash
+	runStatus.LastExecutionID = executionID
+	return nil
+}
+
+// replicationSpecHash hashes the replication rule, leaving out RunRequest and
+// DeletionPolicy so that only changes to the rule itself alter the hash.
+func replicationSpecHash(replication harborconfigurationv1alpha1.Replication) (string, error) {
+	replication.RunRequest = ""
+	replication.DeletionPolicy = ""
+	raw, err := json.Marshal(replication)
+	if err != nil {
+		return "", err
+	}
+
+	hash := fnv.New64a()
+	_, _ = hash.Write(raw)
+	return strconv.FormatUint(hash.Sum64(), 16), nil
+}
+
+// inFlightReplicationExecution returns the ID of the manual execution of the
+// replication rule that started last if it is newer than the last recorded
+// one and still in progress, or 0 when there is none.
+func inFlightReplicationExecution(ctx context.Context, replicationID, lastExecutionID int64, client *harborClient) (int64, error) {
+	execution, err := client.latestReplicationExecution(ctx, replicationID, replicationExecutionInProgress, "manual")
+	if err != nil || execution == nil || execution.ID <= lastExecutionID {
+		return 0, err
+	}
+	return execution.ID, nil
+}
+
+// triggerReplication starts a manual execution of the replication rule and
+// returns the ID of the execution.
+func triggerReplication(ctx context.Context, replicationID int64, client *harborClient) (int64, error) {
+	trigger := &modelv2.StartReplicationExecution{
+		PolicyID: replicationID,
+	}
+	triggeredAt := time.Now()
+	err := client.TriggerReplicationExecution(ctx, trigger)
+	if err != nil {
+		return 0, err
+	}
+
+	// Harbor does not return the execution it started, so pick the manual
+	// execution of the policy that started last, if it started around the
+	// trigger.
+	execution, err := client.latestReplicationExecution(ctx, replicationID, "", "manual")
+	if err != nil {
+		return 0, err
+	}
+	if execution == nil || !startedAround(execution, triggeredAt) {
+		return 0, fmt.Errorf("no execution started at %s found", triggeredAt.UTC().Format(time.RFC3339))
+	}
+	return execution.ID, nil
+}
+
+// startedAround reports whether the execution started at most
+// replicationClockSkew before or after triggeredAt.
+func startedAround(execution *modelv2.ReplicationExecution, triggeredAt time.Time) bool {
+	offset := time.Time(execution.StartTime).Sub(triggeredAt)
+	if offset < 0 {
+		offset = -offset
+	}
+	return offset <= replicationClockSkew
+}
@@ -0,0 +1,291 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	modelv2 "github.com/mittwald/goharbor-client/v5/apiv2/model"
+	"k8s.io/client-go/tools/record"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+// fakeReplicationExecutions serves the manual executions of replication rule
+// 1 and starts a new one with the next ID on every trigger.
+type fakeReplicationExecutions struct {
+	mu         sync.Mutex
+	executions []*modelv2.ReplicationExecution
+	nextID     int64
+	triggered  int
+	fetched    int
+	// listed holds the query of every listing of executions.
+	listed []url.Values
+}
+
+func (f *fakeReplicationExecutions) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/v2.0")
+	switch {
+	case r.Method == http.MethodPost && path == "/replication/executions":
+		f.triggered++
+		f.executions = append(f.executions, &modelv2.ReplicationExecution{
+			ID:        f.nextID,
+			PolicyID:  1,
+			Status:    replicationExecutionInProgress,
+			Trigger:   "manual",
+			StartTime: strfmt.DateTime(time.Now()),
+		})
+		f.nextID++
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodGet && path == "/replication/executions":
+		query := r.URL.Query()
+		f.listed = append(f.listed, query)
+		var executions []*modelv2.ReplicationExecution
+		for _, execution := range f.executions {
+			if status := query.Get("status"); status == "" || execution.Status == status {
+				executions = append(executions, execution)
+			}
+		}
+		if query.Get("sort") == "-start_time" {
+			sort.SliceStable(executions, func(i, j int) bool {
+				return time.Time(executions[i].StartTime).After(time.Time(executions[j].StartTime))
+			})
+		}
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(executions)))
+		page, _ := strconv.Atoi(query.Get("page"))
+		pageSize, _ := strconv.Atoi(query.Get("page_size"))
+		start, end := (page-1)*pageSize, page*pageSize
+		if start > len(executions) {
+			start = len(executions)
+		}
+		if end > len(executions) {
+			end = len(executions)
+		}
+		writeJSON(w, executions[start:end])
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/replication/executions/"):
+		f.fetched++
+		id, _ := strconv.ParseInt(strings.TrimPrefix(path, "/replication/executions/"), 10, 64)
+		for _, execution := range f.executions {
+			if execution.ID == id {
+				writeJSON(w, execution)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestRunReplicationIfRequested(t *testing.T) {
+	replication := harborconfigurationv1alpha1.Replication{
+		Name: "mirror",
+		ReplicationSettings: harborconfigurationv1alpha1.ReplicationSettings{
+			EnablePolicy: true,
+			RunRequest:   "2",
+		},
+	}
+	specHash, err := replicationSpecHash(replication)
+	if err != nil {
+		t.Fatalf("replicationSpecHash() error = %v", err)
+	}
+	disabled := replication
+	disabled.EnablePolicy = false
+	longAgo := strfmt.DateTime(time.Now().Add(-2 * time.Hour))
+
+	tests := []struct {
+		name            string
+		replication     harborconfigurationv1alpha1.Replication
+		runStatus       harborconfigurationv1alpha1.ReplicationRunStatus
+		executions      []*modelv2.ReplicationExecution
+		wantTriggered   int
+		wantExecutionID int64
+		wantEvent       string
+	}{
+		{
+			name:            "unchanged",
+			replication:     replication,
+			runStatus:       harborconfigurationv1alpha1.ReplicationRunStatus{LastRunRequest: "2", LastTriggeredSpecHash: specHash, LastExecutionID: 5},
+			wantExecutionID: 5,
+		},
+		{
+			name:        "disabled",
+			replication: disabled,
+			runStatus:   harborconfigurationv1alpha1.ReplicationRunStatus{LastRunRequest: "1"},
+			wantEvent:   harborconfigurationv1alpha1.ReasonReplicationDisabled,
+		},
+		{
+			name:        "new run request",
+			replication: replication,
+			runStatus:   harborconfigurationv1alpha1.ReplicationRunStatus{LastRunRequest: "1", LastTriggeredSpecHash: specHash, LastExecutionID: 5},
+			executions: []*modelv2.ReplicationExecution{
+				{ID: 5, PolicyID: 1, Status: replicationExecutionSucceed, Trigger: "manual", StartTime: longAgo},
+				// An execution with a higher ID that started long before
+				// the trigger is not the one that was started.
+				{ID: 99, PolicyID: 1, Status: replicationExecutionFailed, Trigger: "manual", StartTime: longAgo},
+			},
+			wantTriggered:   1,
+			wantExecutionID: 10,
+			wantEvent:       harborconfigurationv1alpha1.ReasonReplicationTriggered,
+		},
+		{
+			name:        "changed rule",
+			replication: replication,
+			runStatus:   harborconfigurationv1alpha1.ReplicationRunStatus{LastRunRequest: "2", LastTriggeredSpecHash: "other", LastExecutionID: 5},
+			executions: []*modelv2.ReplicationExecution{
+				{ID: 5, PolicyID: 1, Status: replicationExecutionSucceed, Trigger: "manual", StartTime: longAgo},
+			},
+			wantTriggered:   1,
+			wantExecutionID: 10,
+			wantEvent:       harborconfigurationv1alpha1.ReasonReplicationTriggered,
+		},
+		{
+			name:        "several executions in progress",
+			replication: replication,
+			runStatus:   harborconfigurationv1alpha1.ReplicationRunStatus{LastRunRequest: "1", LastTriggeredSpecHash: specHash, LastExecutionID: 5},
+			executions: []*modelv2.ReplicationExecution{
+				{ID: 7, PolicyID: 1, Status: replicationExecutionInProgress, Trigger: "manual", StartTime: strfmt.DateTime(time.Now())},
+				{ID: 6, PolicyID: 1, Status: replicationExecutionInProgress, Trigger: "manual", StartTime: longAgo},
+				{ID: 8, PolicyID: 1, Status: replicationExecutionSucceed, Trigger: "manual", StartTime: strfmt.DateTime(time.Now())},
+			},
+			wantExecutionID: 7,
+		},
+		{
+			name:        "recorded execution in progress",
+			replication: replication,
+			runStatus:   harborconfigurationv1alpha1.ReplicationRunStatus{LastRunRequest: "1", LastTriggeredSpecHash: specHash, LastExecutionID: 5},
+			executions: []*modelv2.ReplicationExecution{
+				{ID: 5, PolicyID: 1, Status: replicationExecutionInProgress, Trigger: "manual", StartTime: longAgo},
+			},
+			wantTriggered:   1,
+			wantExecutionID: 10,
+			wantEvent:       harborconfigurationv1alpha1.ReasonReplicationTriggered,
+		},
+		{
+			name:        "unrecorded execution in progress",
+			replication: replication,
+			runStatus:   harborconfigurationv1alpha1.ReplicationRunStatus{LastRunRequest: "1", LastTriggeredSpecHash: specHash, LastExecutionID: 5},
+			executions: []*modelv2.ReplicationExecution{
+				{ID: 5, PolicyID: 1, Status: replicationExecutionSucceed, Trigger: "manual", StartTime: longAgo},
+				{ID: 6, PolicyID: 1, Status: replicationExecutionInProgress, Trigger: "manual", StartTime: strfmt.DateTime(time.Now())},
+			},
+			wantExecutionID: 6,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			harbor := &fakeReplicationExecutions{executions: tt.executions, nextID: 10}
+			client := newFakeHarborClient(t, harbor)
+			recorder := record.NewFakeRecorder(10)
+			events := harborEvents{recorder: recorder, object: &harborconfigurationv1alpha1.HarborReplicationPolicy{}}
+
+			runStatus := tt.runStatus
+			if err := runReplicationIfRequested(context.Background(), tt.replication, 1, &runStatus, events, client); err != nil {
+				t.Fatalf("runReplicationIfRequested() error = %v", err)
+			}
+			if harbor.triggered != tt.wantTriggered {
+				t.Errorf("triggered %d executions, want %d", harbor.triggered, tt.wantTriggered)
+			}
+			if runStatus.LastExecutionID != tt.wantExecutionID {
+				t.Errorf("LastExecutionID = %d, want %d", runStatus.LastExecutionID, tt.wantExecutionID)
+			}
+			// Only the execution that started last is ever fetched.
+			for _, query := range harbor.listed {
+				if query.Get("page") != "1" || query.Get("page_size") != "1" || query.Get("sort") != "-start_time" {
+					t.Errorf("executions listed with query %q, want the first one sorted by start time", query.Encode())
+				}
+			}
+
+			var event string
+			select {
+			case event = <-recorder.Events:
+			default:
+			}
+			if !strings.Contains(event, tt.wantEvent) || (tt.wantEvent == "" && event != "") {
+				t.Errorf("event = %q, want reason %q", event, tt.wantEvent)
+			}
+		})
+	}
+}
+
+func TestNextScheduledTime(t *testing.T) {
+	now := time.Date(2022, 6, 30, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		trigger *harborconfigurationv1alpha1.ReplicationTrigger
+		want    *time.Time
+	}{
+		{
+			name: "no trigger",
+		},
+		{
+			name:    "manual",
+			trigger: &harborconfigurationv1alpha1.ReplicationTrigger{Type: "manual"},
+		},
+		{
+			name:    "scheduled without settings",
+			trigger: &harborconfigurationv1alpha1.ReplicationTrigger{Type: "scheduled"},
+		},
+		{
+			name: "invalid cron",
+			trigger: &harborconfigurationv1alpha1.ReplicationTrigger{Type: "scheduled", TriggerSettings: &harborconfigurationv1alpha1.ReplicationTriggerSettings{
+				Cron: "0 0 * * *",
+			}},
+		},
+		{
+			name: "hourly",
+			trigger: &harborconfigurationv1alpha1.ReplicationTrigger{Type: "scheduled", TriggerSettings: &harborconfigurationv1alpha1.ReplicationTriggerSettings{
+				Cron: "0 0 * * * *",
+			}},
+			want: timePtr(time.Date(2022, 6, 30, 13, 0, 0, 0, time.UTC)),
+		},
+		{
+			name: "daily",
+			trigger: &harborconfigurationv1alpha1.ReplicationTrigger{Type: "scheduled", TriggerSettings: &harborconfigurationv1alpha1.ReplicationTriggerSettings{
+				Cron: "0 0 3 * * *",
+			}},
+			want: timePtr(time.Date(2022, 7, 1, 3, 0, 0, 0, time.UTC)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nextScheduledTime(tt.trigger, now)
+			switch {
+			case got == nil && tt.want == nil:
+			case got == nil || tt.want == nil || !got.Time.Equal(*tt.want):
+				t.Errorf("nextScheduledTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,289 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	chain "github.com/g8rswimmer/error-chain"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	controllerutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+const (
+	// robotAccountUIDLabel marks the copies of the Secret of a
+	// HarborRobotAccount with the UID of the resource.
+	robotAccountUIDLabel = "administration.harbor.configuration/robot-account-uid"
+	// robotAccountAnnotation names the HarborRobotAccount a copy of its
+	// Secret belongs to, as <namespace>/<name>.
+	robotAccountAnnotation = "administration.harbor.configuration/robot-account"
+	// pullSecretsNamespaceLabel must be set to "true" on a namespace before
+	// the Secrets of robot accounts in other namespaces are copied into it.
+	pullSecretsNamespaceLabel = "administration.harbor.configuration/pull-secrets"
+	// defaultServiceAccountName is the ServiceAccount of pods that name none.
+	defaultServiceAccountName = "default"
+)
+
+// distributeSecret copies the Secret of the robot account into the namespaces
+// selected by its namespaceSelector and removes the copies from namespaces
+// that are no longer selected. It returns the namespaces holding a copy.
+func (r *HarborRobotAccountReconciler) distributeSecret(ctx context.Context, harborRobotAccount *harborconfigurationv1alpha1.HarborRobotAccount) ([]string, error) {
+	selected, err := r.selectedNamespaces(ctx, harborRobotAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	var secret corev1.Secret
+	err = r.APIReader.Get(ctx, types.NamespacedName{Namespace: harborRobotAccount.Namespace, Name: harborRobotAccount.CredentialsSecretName()}, &secret)
+	if apierrors.IsNotFound(err) {
+		// The Secret is written again by the next reconciliation.
+		return harborRobotAccount.Status.Namespaces, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	errorChain := chain.New()
+	var namespaces []string
+	for _, namespace := range selected {
+		if err := r.copySecret(ctx, harborRobotAccount, &secret, namespace); err != nil {
+			errorChain.Add(err)
+			continue
+		}
+		namespaces = append(namespaces, namespace)
+	}
+
+	keep := make(map[string]bool, len(selected))
+	for _, namespace := range selected {
+		keep[namespace] = true
+	}
+	if err := r.removeSecretCopies(ctx, harborRobotAccount, keep); err != nil {
+		errorChain.Add(err)
+	}
+
+	if harborRobotAccount.Spec.PatchDefaultServiceAccount {
+		for _, namespace := range append([]string{harborRobotAccount.Namespace}, namespaces...) {
+			if err := r.setImagePullSecret(ctx, namespace, secret.Name, true); err != nil {
+				errorChain.Add(err)
+			}
+		}
+	}
+
+	if len(errorChain.Errors()) > 0 {
+		return namespaces, errorChain
+	}
+	return namespaces, nil
+}
+
+// selectedNamespaces returns the sorted namespaces matching the
+// namespaceSelector of the robot account that opted in to receiving pull
+// secrets through the pullSecretsNamespaceLabel, except its own and
+// terminating ones.
+func (r *HarborRobotAccountReconciler) selectedNamespaces(ctx context.Context, harborRobotAccount *harborconfigurationv1alpha1.HarborRobotAccount) ([]string, error) {
+	namespaceSelector := harborRobotAccount.Spec.NamespaceSelector
+	if namespaceSelector == nil {
+		return nil, nil
+	}
+	// An empty selector matches every namespace.
+	if len(namespaceSelector.MatchLabels) == 0 && len(namespaceSelector.MatchExpressions) == 0 {
+		return nil, errors.New("namespaceSelector must not be empty")
+	}
+	selector, err := v1.LabelSelectorAsSelector(namespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+	optIn, err := labels.NewRequirement(pullSecretsNamespaceLabel, selection.Equals, []string{"true"})
+	if err != nil {
+		return nil, err
+	}
+	selector = selector.Add(*optIn)
+
+	namespaceList := &v1.PartialObjectMetadataList{}
+	namespaceList.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("NamespaceList"))
+	err = r.List(ctx, namespaceList, client.MatchingLabelsSelector{Selector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	var namespaces []string
+	for _, namespace := range namespaceList.Items {
+		if namespace.Name == harborRobotAccount.Namespace || !namespace.DeletionTimestamp.IsZero() {
+			continue
+		}
+		namespaces = append(namespaces, namespace.Name)
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+// copySecret writes a copy of the Secret to the namespace, refusing to
+// overwrite a Secret that is not a copy made for the robot account.
+func (r *HarborRobotAccountReconciler) copySecret(ctx context.Context, harborRobotAccount *harborconfigurationv1alpha1.HarborRobotAccount, secret *corev1.Secret, namespace string) error {
+	secretCopy := &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{
+			Namespace: namespace,
+			Name:      secret.Name,
+		},
+	}
+	return r.createOrUpdateSecret(ctx, secretCopy, func() error {
+		if !secretCopy.CreationTimestamp.IsZero() && secretCopy.Labels[robotAccountUIDLabel] != string(harborRobotAccount.UID) {
+			return fmt.Errorf("secret %s/%s already exists and is not a copy made for the HarborRobotAccount", secretCopy.Namespace, secretCopy.Name)
+		}
+		if secretCopy.Labels == nil {
+			secretCopy.Labels = map[string]string{}
+		}
+		secretCopy.Labels[robotAccountUIDLabel] = string(harborRobotAccount.UID)
+		if secretCopy.Annotations == nil {
+			secretCopy.Annotations = map[string]string{}
+		}
+		secretCopy.Annotations[robotAccountAnnotation] = client.ObjectKeyFromObject(harborRobotAccount).String()
+		secretCopy.Type = secret.Type
+		secretCopy.Data = secret.Data
+		return nil
+	})
+}
+
+// createOrUpdateSecret works like controllerutil.CreateOrUpdate but reads the
+// Secret through the APIReader, as Secrets are not cached.
+func (r *HarborRobotAccountReconciler) createOrUpdateSecret(ctx context.Context, secret *corev1.Secret, mutate controllerutil.MutateFn) error {
+	err := r.APIReader.Get(ctx, client.ObjectKeyFromObject(secret), secret)
+	if apierrors.IsNotFound(err) {
+		if err := mutate(); err != nil {
+			return err
+		}
+		return r.Create(ctx, secret)
+	}
+	if err != nil {
+		return err
+	}
+
+	existing := secret.DeepCopy()
+	if err := mutate(); err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(existing, secret) {
+		return nil
+	}
+	return r.Update(ctx, secret)
+}
+
+// removeSecretCopies deletes the copies of the Secret of the robot account
+// outside the kept namespaces, or under a previous name, together with their
+// entry in the imagePullSecrets of the default ServiceAccount.
+func (r *HarborRobotAccountReconciler) removeSecretCopies(ctx context.Context, harborRobotAccount *harborconfigurationv1alpha1.HarborRobotAccount, keep map[string]bool) error {
+	copies := &v1.PartialObjectMetadataList{}
+	copies.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("SecretList"))
+	err := r.List(ctx, copies, client.MatchingLabels{robotAccountUIDLabel: string(harborRobotAccount.UID)})
+	if err != nil {
+		return err
+	}
+
+	errorChain := chain.New()
+	for i := range copies.Items {
+		secretCopy := &copies.Items[i]
+		if keep[secretCopy.Namespace] && secretCopy.Name == harborRobotAccount.CredentialsSecretName() {
+			continue
+		}
+		if err := r.setImagePullSecret(ctx, secretCopy.Namespace, secretCopy.Name, false); err != nil {
+			errorChain.Add(err)
+			continue
+		}
+		if err := r.Delete(ctx, secretCopy); client.IgnoreNotFound(err) != nil {
+			errorChain.Add(err)
+		}
+	}
+
+	if len(errorChain.Errors()) > 0 {
+		return errorChain
+	}
+	return nil
+}
+
+// setImagePullSecret adds the Secret to, or removes it from, the
+// imagePullSecrets of the default ServiceAccount of the namespace.
+func (r *HarborRobotAccountReconciler) setImagePullSecret(ctx context.Context, namespace, secretName string, present bool) error {
+	var serviceAccount corev1.ServiceAccount
+	err := r.APIReader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: defaultServiceAccountName}, &serviceAccount)
+	if apierrors.IsNotFound(err) && !present {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	index := -1
+	for i, ref := range serviceAccount.ImagePullSecrets {
+		if ref.Name == secretName {
+			index = i
+			break
+		}
+	}
+	if present == (index >= 0) {
+		return nil
+	}
+
+	patch := client.MergeFromWithOptions(serviceAccount.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	if present {
+		serviceAccount.ImagePullSecrets = append(serviceAccount.ImagePullSecrets, corev1.LocalObjectReference{Name: secretName})
+	} else {
+		serviceAccount.ImagePullSecrets = append(serviceAccount.ImagePullSecrets[:index], serviceAccount.ImagePullSecrets[index+1:]...)
+	}
+	return r.Patch(ctx, &serviceAccount, patch)
+}
+
+// findRobotAccountsForNamespace maps a Namespace to the HarborRobotAccounts
+// that may have to copy their Secret into it or remove it from there.
+func (r *HarborRobotAccountReconciler) findRobotAccountsForNamespace(namespace client.Object) []reconcile.Request {
+	var harborRobotAccounts harborconfigurationv1alpha1.HarborRobotAccountList
+	err := r.List(context.Background(), &harborRobotAccounts)
+	if err != nil {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, item := range harborRobotAccounts.Items {
+		if item.Spec.NamespaceSelector == nil && len(item.Status.Namespaces) == 0 {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&item)})
+	}
+	return requests
+}
+
+// findRobotAccountForSecretCopy maps a copy of a Secret to the
+// HarborRobotAccount it was made for.
+func (r *HarborRobotAccountReconciler) findRobotAccountForSecretCopy(secret client.Object) []reconcile.Request {
+	namespace, name, ok := strings.Cut(secret.GetAnnotations()[robotAccountAnnotation], "/")
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: namespace, Name: name}}}
+}
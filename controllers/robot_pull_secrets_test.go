@@ -0,0 +1,124 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	harborconfigurationv1alpha1 "github.com/giantswarm/harbor-config-operator/api/v1alpha1"
+)
+
+func TestSelectedNamespaces(t *testing.T) {
+	namespace := func(name string, labels map[string]string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+	reader := fake.NewClientBuilder().WithObjects(
+		namespace("ci", map[string]string{"team": "a"}),
+		namespace("team-a", map[string]string{"team": "a", pullSecretsNamespaceLabel: "true"}),
+		namespace("team-a-dev", map[string]string{"team": "a", pullSecretsNamespaceLabel: "true"}),
+		namespace("team-a-old", map[string]string{"team": "a", pullSecretsNamespaceLabel: "false"}),
+		namespace("team-b", map[string]string{"team": "b", pullSecretsNamespaceLabel: "true"}),
+	).Build()
+
+	tests := []struct {
+		name     string
+		selector *metav1.LabelSelector
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name: "no selector",
+		},
+		{
+			name:     "empty selector",
+			selector: &metav1.LabelSelector{},
+			wantErr:  true,
+		},
+		{
+			name:     "empty match labels",
+			selector: &metav1.LabelSelector{MatchLabels: map[string]string{}},
+			wantErr:  true,
+		},
+		{
+			name:     "only namespaces that opted in",
+			selector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+			want:     []string{"team-a-dev"},
+		},
+		{
+			name: "match expressions",
+			selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "team", Operator: metav1.LabelSelectorOpIn, Values: []string{"a", "b"}},
+			}},
+			want: []string{"team-a-dev", "team-b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &HarborRobotAccountReconciler{Client: reader}
+			harborRobotAccount := &harborconfigurationv1alpha1.HarborRobotAccount{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "pull"},
+				Spec:       harborconfigurationv1alpha1.HarborRobotAccountSpec{NamespaceSelector: tt.selector},
+			}
+			got, err := r.selectedNamespaces(context.Background(), harborRobotAccount)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectedNamespaces() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectedNamespaces() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCopySecret(t *testing.T) {
+	harborRobotAccount := &harborconfigurationv1alpha1.HarborRobotAccount{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "pull", UID: "1234"},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "pull"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{}}`)},
+	}
+	foreign := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "team-c", Name: "pull", CreationTimestamp: metav1.Now()}}
+	k8sClient := fake.NewClientBuilder().WithObjects(foreign).Build()
+	r := &HarborRobotAccountReconciler{Client: k8sClient, APIReader: k8sClient}
+	ctx := context.Background()
+
+	for _, namespace := range []string{"team-b", "team-b"} {
+		if err := r.copySecret(ctx, harborRobotAccount, secret, namespace); err != nil {
+			t.Fatalf("copySecret(%q) error = %v", namespace, err)
+		}
+	}
+	var secretCopy corev1.Secret
+	if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "team-b", Name: "pull"}, &secretCopy); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !reflect.DeepEqual(secretCopy.Data, secret.Data) || secretCopy.Labels[robotAccountUIDLabel] != "1234" {
+		t.Errorf("copy = %+v, want the data of the Secret labelled with the robot account UID", secretCopy)
+	}
+
+	if err := r.copySecret(ctx, harborRobotAccount, secret, "team-c"); err == nil {
+		t.Error("copySecret() overwrote a Secret that is not a copy")
+	}
+}
@@ -21,11 +21,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 
-	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/rest"
@@ -60,8 +60,21 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var resyncInterval time.Duration
+	var allowSystemRobotAccounts bool
+	var defaultTarget harborconfigurationv1alpha1.HarborTarget
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.DurationVar(&resyncInterval, "resync-interval", 10*time.Minute,
+		"How often Harbor objects are compared with their spec to correct drift. Zero disables the periodic resync.")
+	flag.StringVar(&defaultTarget.Name, "default-harbor-target-name", "",
+		"The HarborCluster used by HarborConfigurations that do not set spec.harborTarget.name.")
+	flag.StringVar(&defaultTarget.Namespace, "default-harbor-target-namespace", "",
+		"The namespace of the HarborCluster used by HarborConfigurations that do not set spec.harborTarget.namespace.")
+	flag.StringVar(&defaultTarget.HarborUsername, "default-harbor-username", "admin",
+		"The Harbor user used by HarborConfigurations that do not set spec.harborTarget.harborUsername.")
+	flag.BoolVar(&allowSystemRobotAccounts, "allow-system-robot-accounts", false,
+		"Allow HarborRobotAccounts with level system, which can be granted permissions on every Harbor project.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
@@ -86,15 +99,72 @@ func main() {
 		os.Exit(1)
 	}
 
+	clientSet := getTypedKubeConfig()
+	harborClients := controllers.NewHarborClientPool(controllers.NewHarborTargetReader(mgr.GetCache(), mgr.GetAPIReader(), mgr.GetScheme()), clientSet.Discovery())
+
 	if err = (&controllers.HarborConfigurationReconciler{
-		ClientSet:  getTypedKubeConfig(),
-		DynamicSet: getDynamicKubeConfig(),
-		Client:     mgr.GetClient(),
-		Scheme:     mgr.GetScheme(),
+		ClientSet:      clientSet,
+		HarborClients:  harborClients,
+		Client:         mgr.GetClient(),
+		Scheme:         mgr.GetScheme(),
+		Recorder:       mgr.GetEventRecorderFor("harbor-config-operator"),
+		ResyncInterval: resyncInterval,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "HarborConfiguration")
 		os.Exit(1)
 	}
+	if err = (&controllers.HarborRegistryReconciler{
+		ClientSet:      clientSet,
+		HarborClients:  harborClients,
+		Client:         mgr.GetClient(),
+		Scheme:         mgr.GetScheme(),
+		Recorder:       mgr.GetEventRecorderFor("harbor-config-operator"),
+		ResyncInterval: resyncInterval,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "HarborRegistry")
+		os.Exit(1)
+	}
+	if err = (&controllers.HarborProjectReconciler{
+		ClientSet:      clientSet,
+		HarborClients:  harborClients,
+		Client:         mgr.GetClient(),
+		Scheme:         mgr.GetScheme(),
+		Recorder:       mgr.GetEventRecorderFor("harbor-config-operator"),
+		ResyncInterval: resyncInterval,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "HarborProject")
+		os.Exit(1)
+	}
+	if err = (&controllers.HarborReplicationPolicyReconciler{
+		ClientSet:      clientSet,
+		HarborClients:  harborClients,
+		Client:         mgr.GetClient(),
+		Scheme:         mgr.GetScheme(),
+		Recorder:       mgr.GetEventRecorderFor("harbor-config-operator"),
+		ResyncInterval: resyncInterval,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "HarborReplicationPolicy")
+		os.Exit(1)
+	}
+	if err = (&controllers.HarborRobotAccountReconciler{
+		HarborClients:  harborClients,
+		Client:         mgr.GetClient(),
+		Scheme:         mgr.GetScheme(),
+		Recorder:       mgr.GetEventRecorderFor("harbor-config-operator"),
+		ResyncInterval: resyncInterval,
+
+		APIReader:                mgr.GetAPIReader(),
+		AllowSystemRobotAccounts: allowSystemRobotAccounts,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "HarborRobotAccount")
+		os.Exit(1)
+	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&harborconfigurationv1alpha1.HarborConfiguration{}).SetupWebhookWithManager(mgr, defaultTarget, controllers.HarborRegistryExists(harborClients)); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "HarborConfiguration")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -113,27 +183,6 @@ func main() {
 	}
 }
 
-func getDynamicKubeConfig() dynamic.Interface {
-	var config *rest.Config
-
-	config, err := rest.InClusterConfig()
-	if err != nil {
-		config = getkubeConfig()
-		if config == nil {
-			setupLog.Error(err, "could not get dynamic configuration")
-			os.Exit(1)
-		}
-	}
-
-	dynamicClient, err := dynamic.NewForConfig(config)
-	if err != nil {
-		fmt.Printf("error creating dynamic client: %v\n", err)
-		os.Exit(1)
-	}
-
-	return dynamicClient
-}
-
 func getTypedKubeConfig() *kubernetes.Clientset {
 	var config *rest.Config
 